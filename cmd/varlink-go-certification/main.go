@@ -76,7 +76,7 @@ func run_client(ctx context.Context, address string) {
 	}
 	fmt.Printf("Test06: '%v'\n", o6)
 
-	m7, err := orgvarlinkcertification.Test07().Call(ctx, c, client_id, o6)
+	m7, err := orgvarlinkcertification.Test07().Call(ctx, c, client_id, orgvarlinkcertification.Test07_In_Struct(o6))
 	if err != nil {
 		fmt.Println("Test07() failed")
 		return
@@ -120,7 +120,7 @@ func run_client(ctx context.Context, address string) {
 	}
 	fmt.Printf("Test10: '%v'\n", a10)
 
-	_, err = orgvarlinkcertification.Test11().Send(ctx, c, varlink.Oneway, client_id, a10)
+	err = orgvarlinkcertification.Test11().Oneway(ctx, c, client_id, a10)
 	if err != nil {
 		fmt.Println("Test11() failed")
 		return
@@ -271,12 +271,7 @@ func (t *test) Test06(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	s := struct {
-		Bool   bool
-		Int    int64
-		Float  float64
-		String string
-	}{
+	s := orgvarlinkcertification.Test06_Out_Struct{
 		Bool:   false,
 		Int:    2,
 		Float:  math.Pi,
@@ -285,12 +280,7 @@ func (t *test) Test06(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 	return c.ReplyTest06(ctx, s)
 }
 
-func (t *test) Test07(ctx context.Context, c orgvarlinkcertification.VarlinkCall, client_id_ string, struct_ struct {
-	Bool   bool
-	Int    int64
-	Float  float64
-	String string
-}) error {
+func (t *test) Test07(ctx context.Context, c orgvarlinkcertification.VarlinkCall, client_id_ string, struct_ orgvarlinkcertification.Test07_In_Struct) error {
 	if t.Client(client_id_) == nil {
 		return c.ReplyClientIdError(ctx)
 	}
@@ -368,28 +358,28 @@ func (t *test) Test09(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 	}
 
 	m := orgvarlinkcertification.MyType{
-		Object: json.RawMessage(`{"method": "org.varlink.certification.Test09", "parameters": {"map": {"foo": "Foo", "bar": "Bar"}}}`),
-		Enum:   "two",
-		Struct: struct {
-			First  int64  `json:"first"`
-			Second string `json:"second"`
-		}{First: 1, Second: "2"},
-		Array:                 []string{"one", "two", "three"},
-		Dictionary:            map[string]string{"foo": "Foo", "bar": "Bar"},
-		Stringset:             map[string]struct{}{"one": {}, "two": {}, "three": {}},
-		Nullable:              nil,
-		Nullable_array_struct: nil,
+		Object:              json.RawMessage(`{"method": "org.varlink.certification.Test09", "parameters": {"map": {"foo": "Foo", "bar": "Bar"}}}`),
+		Enum:                orgvarlinkcertification.MyType_Enum_Two,
+		Struct:              orgvarlinkcertification.MyType_Struct{First: 1, Second: "2"},
+		Array:               []string{"one", "two", "three"},
+		Dictionary:          map[string]string{"foo": "Foo", "bar": "Bar"},
+		Stringset:           map[string]struct{}{"one": {}, "two": {}, "three": {}},
+		Nullable:            nil,
+		NullableArrayStruct: nil,
 		Interface: orgvarlinkcertification.Interface{
-			Foo: &[]*map[string]string{
+			Foo: &[]*map[string]orgvarlinkcertification.Interface_Foo{
 				nil,
-				&map[string]string{"Foo": "foo", "Bar": "bar"},
+				&map[string]orgvarlinkcertification.Interface_Foo{
+					"Foo": orgvarlinkcertification.Interface_Foo_Foo,
+					"Bar": orgvarlinkcertification.Interface_Foo_Bar,
+				},
 				nil,
-				&map[string]string{"one": "foo", "two": "bar"},
+				&map[string]orgvarlinkcertification.Interface_Foo{
+					"one": orgvarlinkcertification.Interface_Foo_Foo,
+					"two": orgvarlinkcertification.Interface_Foo_Bar,
+				},
 			},
-			Anon: struct {
-				Foo bool `json:"foo"`
-				Bar bool `json:"bar"`
-			}{Foo: true, Bar: false},
+			Anon: orgvarlinkcertification.Interface_Anon{Foo: true, Bar: false},
 		},
 	}
 	return c.ReplyTest09(ctx, m)
@@ -427,7 +417,7 @@ func (t *test) Test10(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	if mytype_.Enum != "two" {
+	if mytype_.Enum != orgvarlinkcertification.MyType_Enum_Two {
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
@@ -482,7 +472,7 @@ func (t *test) Test10(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	if mytype_.Nullable_array_struct != nil {
+	if mytype_.NullableArrayStruct != nil {
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
@@ -499,11 +489,11 @@ func (t *test) Test10(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	if (*i[1])["Foo"] != "foo" {
+	if (*i[1])["Foo"] != orgvarlinkcertification.Interface_Foo_Foo {
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	if (*i[1])["Bar"] != "bar" {
+	if (*i[1])["Bar"] != orgvarlinkcertification.Interface_Foo_Bar {
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
@@ -515,11 +505,11 @@ func (t *test) Test10(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	if (*i[3])["one"] != "foo" {
+	if (*i[3])["one"] != orgvarlinkcertification.Interface_Foo_Foo {
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	if (*i[3])["two"] != "bar" {
+	if (*i[3])["two"] != orgvarlinkcertification.Interface_Foo_Bar {
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
@@ -535,15 +525,14 @@ func (t *test) Test10(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 		return c.ReplyCertificationError(ctx, nil, nil)
 	}
 
-	for i := 1; i <= 10; i++ {
-		c.Continues = i < 10
-		err := c.ReplyTest10(ctx, "Reply number "+strconv.Itoa(i))
+	for i := 1; i < 10; i++ {
+		err := c.StreamTest10(ctx, "Reply number "+strconv.Itoa(i))
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return c.FinishTest10(ctx, "Reply number 10")
 }
 
 func (t *test) Test11(ctx context.Context, c orgvarlinkcertification.VarlinkCall, client_id_ string, last_more_replies_ []string) error {
@@ -560,7 +549,7 @@ func (t *test) Test11(ctx context.Context, c orgvarlinkcertification.VarlinkCall
 	}
 
 	for i := 1; i <= 10; i++ {
-		if last_more_replies_[i] != "Reply number "+strconv.Itoa(i) {
+		if last_more_replies_[i-1] != "Reply number "+strconv.Itoa(i) {
 			return c.ReplyCertificationError(ctx, nil, nil)
 		}
 	}
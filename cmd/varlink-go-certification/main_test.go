@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/varlink/go/cmd/varlink-go-certification/orgvarlinkcertification"
+	"github.com/varlink/go/varlink"
+)
+
+// newTestConnection starts the certification service and connects to it.
+func newTestConnection(t *testing.T) *varlink.Connection {
+	service, err := varlink.NewService("Varlink", "Certification", "1", "https://github.com/varlink/go")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(orgvarlinkcertification.VarlinkNew(&test{clients: make(map[string]*client)})); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	address := "unix:" + filepath.Join(t.TempDir(), "service")
+	if err := service.Bind(ctx, address); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+	servererror := make(chan error)
+	go func() {
+		servererror <- service.DoListen(ctx, 0)
+	}()
+
+	c, err := varlink.NewConnection(ctx, address)
+	if err != nil {
+		t.Fatalf("NewConnection(): %v", err)
+	}
+
+	t.Cleanup(func() {
+		c.Close()
+		service.Shutdown()
+		if err := <-servererror; err != nil {
+			t.Errorf("service.DoListen(): %v", err)
+		}
+		cancel()
+	})
+	return c
+}
+
+func TestClient(t *testing.T) {
+	c := newTestConnection(t)
+	ctx := context.Background()
+
+	var client orgvarlinkcertification.VarlinkClient = orgvarlinkcertification.VarlinkNewClient(c)
+	id, err := client.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+	ok, err := client.Test01(ctx, id)
+	if err != nil || !ok {
+		t.Fatalf("Test01(): %v, %v", ok, err)
+	}
+	if _, err := client.Test01(ctx, "unknown"); !errors.Is(err, orgvarlinkcertification.ErrClientIdError) {
+		t.Fatalf("Test01() with an unknown client id: %v", err)
+	}
+	_, err = client.Test02(ctx, id, false)
+	var e *orgvarlinkcertification.CertificationError
+	if !errors.As(err, &e) || errors.Is(err, orgvarlinkcertification.ErrClientIdError) {
+		t.Fatalf("Test02(): %v", err)
+	}
+}
+
+func TestOneway(t *testing.T) {
+	c := newTestConnection(t)
+	ctx := context.Background()
+
+	id, err := orgvarlinkcertification.Start().Call(ctx, c)
+	if err != nil {
+		t.Fatalf("Start(): %v", err)
+	}
+
+	var replies []string
+	for i := 1; i <= 10; i++ {
+		replies = append(replies, "Reply number "+strconv.Itoa(i))
+	}
+	if err := orgvarlinkcertification.Test11().Oneway(ctx, c, id, replies); err != nil {
+		t.Fatalf("Test11().Oneway(): %v", err)
+	}
+
+	// The receiver of a oneway call returns without reading a reply
+	receive, err := orgvarlinkcertification.Test11().Send(ctx, c, varlink.Oneway, id, replies)
+	if err != nil {
+		t.Fatalf("Test11().Send(): %v", err)
+	}
+	if _, err := receive(ctx); err != nil {
+		t.Fatalf("receive(): %v", err)
+	}
+
+	// The next reply on the connection belongs to the next call
+	ok, err := orgvarlinkcertification.End().Call(ctx, c, id)
+	if err != nil || !ok {
+		t.Fatalf("End(): %v, %v", ok, err)
+	}
+}
+
+func TestClientMock(t *testing.T) {
+	mock := &orgvarlinkcertification.VarlinkClientMock{
+		Test02Func: func(ctx context.Context, client_id string, b bool) (int64, error) {
+			return 2, nil
+		},
+	}
+
+	var client orgvarlinkcertification.VarlinkClient = mock
+	ctx := context.Background()
+
+	i, err := client.Test02(ctx, "id", true)
+	if err != nil || i != 2 {
+		t.Fatalf("Test02(): %v, %v", i, err)
+	}
+
+	_, err = client.Test03(ctx, "id", i)
+	if e, ok := err.(*varlink.MethodNotImplemented); !ok || e.Method != "org.varlink.certification.Test03" {
+		t.Fatalf("Test03(): %v", err)
+	}
+
+	calls := mock.VarlinkCalls()
+	if len(calls) != 2 {
+		t.Fatalf("Recorded %d calls", len(calls))
+	}
+	if calls[0].Method != "Test02" || calls[0].Parameters[0] != "id" || calls[0].Parameters[1] != true {
+		t.Fatalf("Recorded call %v", calls[0])
+	}
+	if calls[1].Method != "Test03" || calls[1].Parameters[1] != int64(2) {
+		t.Fatalf("Recorded call %v", calls[1])
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/varlink/go/varlink"
+	"github.com/varlink/go/varlink/idl"
+)
+
+const testDescription = `# Interface to jump a spacecraft to another point in space.
+interface org.example.ftl
+
+# The galactic coordinates use the Sun as the origin.
+type Coordinate (
+  longitude: float,
+  latitude: float,
+  distance: int
+)
+
+type State (idle, busy)
+
+# Calculate the drive's jump parameters
+method CalculateConfiguration(
+  current: Coordinate,
+  target: ?[]Coordinate
+) -> (speed: int, state: State)
+
+# Jump to the calculated point in space
+method Jump() -> ()
+
+# The supplied parameters are outside the supported range
+error ParameterOutOfRange (field: string)
+`
+
+func contains(t *testing.T, doc string, expected ...string) {
+	for _, s := range expected {
+		if !strings.Contains(doc, s) {
+			t.Fatalf("Expected `%s` in:\n%s", s, doc)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	midl, err := idl.New(testDescription)
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+
+	doc := string(generateDoc(midl, false))
+	contains(t, doc,
+		"# org.example.ftl\n\nInterface to jump a spacecraft to another point in space.\n",
+		"<a id=\"method-CalculateConfiguration\"></a>\n### CalculateConfiguration\n\nCalculate the drive's jump parameters\n",
+		"method CalculateConfiguration(current: Coordinate, target: ?[]Coordinate) -> (speed: int, state: State)",
+		"| current | [Coordinate](#type-Coordinate) |",
+		"| target | ?\\[\\][Coordinate](#type-Coordinate) |",
+		"<a id=\"error-ParameterOutOfRange\"></a>",
+		"<a id=\"type-Coordinate\"></a>\n### Coordinate\n\nThe galactic coordinates use the Sun as the origin.\n",
+		"- idle\n- busy\n",
+	)
+
+	midl.Doc = "Returns *all* <items> of a_b | c"
+	doc = string(generateDoc(midl, false))
+	contains(t, doc, "# org.example.ftl\n\nReturns \\*all\\* \\<items\\> of a\\_b \\| c\n")
+}
+
+func TestHTML(t *testing.T) {
+	midl, err := idl.New(testDescription)
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	midl.Doc = "<script>"
+
+	doc := string(generateDoc(midl, true))
+	contains(t, doc,
+		"<!DOCTYPE html>",
+		"<title>org.example.ftl</title>",
+		"<p>&lt;script&gt;</p>",
+		"<h3 id=\"method-Jump\">Jump</h3>",
+		"<td>target</td><td>?[]<a href=\"#type-Coordinate\">Coordinate</a></td>",
+		"<pre><code>method Jump() -&gt; ()</code></pre>",
+		"</html>\n",
+	)
+	if strings.Contains(doc, "<script>") {
+		t.Fatal("Doc string was not escaped")
+	}
+}
+
+type testInterface struct{}
+
+func (s *testInterface) VarlinkDispatch(ctx context.Context, call varlink.Call, methodname string) error {
+	return call.ReplyMethodNotImplemented(ctx, methodname)
+}
+
+func (s *testInterface) VarlinkGetName() string {
+	return `org.example.ftl`
+}
+
+func (s *testInterface) VarlinkGetDescription() string {
+	return testDescription
+}
+
+func TestFetchDescriptions(t *testing.T) {
+	service, err := varlink.NewService("Varlink", "Doc Test", "1", "https://github.com/varlink/go")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(new(testInterface)); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	address := "unix:" + filepath.Join(t.TempDir(), "service")
+	if err := service.Bind(ctx, address); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+	servererror := make(chan error)
+	go func() {
+		servererror <- service.DoListen(ctx, 0)
+	}()
+
+	info, descriptions, err := fetchDescriptions(ctx, address)
+	if err != nil {
+		t.Fatalf("fetchDescriptions(): %v", err)
+	}
+
+	if len(descriptions) != 2 || info.product != "Doc Test" {
+		t.Fatalf("Unexpected service information: %v", info)
+	}
+	var idls []*idl.IDL
+	for _, description := range descriptions {
+		midl, err := idl.New(description)
+		if err != nil {
+			t.Fatalf("Error parsing %v", err)
+		}
+		idls = append(idls, midl)
+	}
+
+	output := t.TempDir()
+	if err := writeDocs(output, info, idls, false); err != nil {
+		t.Fatalf("writeDocs(): %v", err)
+	}
+	for _, name := range []string{"org.example.ftl.md", "org.varlink.service.md"} {
+		if _, err := os.Stat(filepath.Join(output, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := os.ReadFile(filepath.Join(output, "index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contains(t, string(index), "# Doc Test\n", "- [org.example.ftl](org.example.ftl.md)\n")
+
+	service.Shutdown()
+	if err := <-servererror; err != nil {
+		t.Fatalf("service.DoListen(): %v", err)
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/varlink/go/varlink"
+	"github.com/varlink/go/varlink/idl"
+)
+
+// serviceInfo is the information returned by org.varlink.service.GetInfo.
+type serviceInfo struct {
+	vendor     string
+	product    string
+	version    string
+	url        string
+	interfaces []string
+}
+
+// writer renders the parts of a reference page in one output format.
+type writer interface {
+	heading(level int, anchor string, text string)
+	doc(s string)
+	code(s string)
+	table(header []string, rows [][]string)
+	list(items []string)
+	text(s string) string
+	link(href string, text string) string
+}
+
+type markdownWriter struct {
+	b strings.Builder
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`,
+)
+
+func (w *markdownWriter) heading(level int, anchor string, text string) {
+	if anchor != "" {
+		w.b.WriteString("<a id=\"" + anchor + "\"></a>\n")
+	}
+	w.b.WriteString(strings.Repeat("#", level) + " " + w.text(text) + "\n\n")
+}
+
+func (w *markdownWriter) doc(s string) {
+	if s == "" {
+		return
+	}
+	w.b.WriteString(w.text(s) + "\n\n")
+}
+
+func (w *markdownWriter) code(s string) {
+	w.b.WriteString("```\n" + s + "\n```\n\n")
+}
+
+func (w *markdownWriter) table(header []string, rows [][]string) {
+	w.b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	w.b.WriteString(strings.Repeat("| --- ", len(header)) + "|\n")
+	for _, row := range rows {
+		w.b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	w.b.WriteString("\n")
+}
+
+func (w *markdownWriter) list(items []string) {
+	for _, item := range items {
+		w.b.WriteString("- " + item + "\n")
+	}
+	w.b.WriteString("\n")
+}
+
+func (w *markdownWriter) text(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func (w *markdownWriter) link(href string, text string) string {
+	return "[" + text + "](" + href + ")"
+}
+
+type htmlWriter struct {
+	b strings.Builder
+}
+
+func (w *htmlWriter) heading(level int, anchor string, text string) {
+	h := fmt.Sprintf("h%d", level)
+	if anchor != "" {
+		w.b.WriteString("<" + h + " id=\"" + html.EscapeString(anchor) + "\">")
+	} else {
+		w.b.WriteString("<" + h + ">")
+	}
+	w.b.WriteString(w.text(text) + "</" + h + ">\n")
+}
+
+func (w *htmlWriter) doc(s string) {
+	if s == "" {
+		return
+	}
+	w.b.WriteString("<p>" + w.text(s) + "</p>\n")
+}
+
+func (w *htmlWriter) code(s string) {
+	w.b.WriteString("<pre><code>" + w.text(s) + "</code></pre>\n")
+}
+
+func (w *htmlWriter) table(header []string, rows [][]string) {
+	w.b.WriteString("<table>\n<tr>")
+	for _, h := range header {
+		w.b.WriteString("<th>" + h + "</th>")
+	}
+	w.b.WriteString("</tr>\n")
+	for _, row := range rows {
+		w.b.WriteString("<tr>")
+		for _, cell := range row {
+			w.b.WriteString("<td>" + cell + "</td>")
+		}
+		w.b.WriteString("</tr>\n")
+	}
+	w.b.WriteString("</table>\n")
+}
+
+func (w *htmlWriter) list(items []string) {
+	w.b.WriteString("<ul>\n")
+	for _, item := range items {
+		w.b.WriteString("<li>" + item + "</li>\n")
+	}
+	w.b.WriteString("</ul>\n")
+}
+
+func (w *htmlWriter) text(s string) string {
+	return html.EscapeString(s)
+}
+
+func (w *htmlWriter) link(href string, text string) string {
+	return "<a href=\"" + html.EscapeString(href) + "\">" + text + "</a>"
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: auto; padding: 1em; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+td { font-family: monospace; }
+p { white-space: pre-line; }
+</style>
+</head>
+<body>
+`
+
+const htmlFooter = `</body>
+</html>
+`
+
+func newWriter(useHTML bool) writer {
+	if useHTML {
+		return &htmlWriter{}
+	}
+	return &markdownWriter{}
+}
+
+func finish(w writer, title string) []byte {
+	switch w := w.(type) {
+	case *htmlWriter:
+		return []byte(fmt.Sprintf(htmlHeader, html.EscapeString(title)) + w.b.String() + htmlFooter)
+	case *markdownWriter:
+		return []byte(w.b.String())
+	}
+	return nil
+}
+
+// writeType renders a type in varlink syntax with links to the definitions of
+// the named types of the interface.
+func writeType(w writer, midl *idl.IDL, t *idl.Type) string {
+	switch t.Kind {
+	case idl.TypeArray:
+		return w.text("[]") + writeType(w, midl, t.ElementType)
+
+	case idl.TypeMap:
+		return w.text("[string]") + writeType(w, midl, t.ElementType)
+
+	case idl.TypeMaybe:
+		return w.text("?") + writeType(w, midl, t.ElementType)
+
+	case idl.TypeAlias:
+		if midl.LookupType(t.Alias) != nil {
+			return w.link("#type-"+t.Alias, w.text(t.Alias))
+		}
+		return w.text(t.Alias)
+
+	case idl.TypeStruct, idl.TypeEnum:
+		s := w.text("(")
+		for i, f := range t.Fields {
+			if i > 0 {
+				s += w.text(", ")
+			}
+			s += w.text(f.Name)
+			if f.Type != nil {
+				s += w.text(": ") + writeType(w, midl, f.Type)
+			}
+		}
+		return s + w.text(")")
+	}
+
+	return w.text(t.String())
+}
+
+func writeFields(w writer, midl *idl.IDL, t *idl.Type, empty string) {
+	if t == nil || len(t.Fields) == 0 {
+		w.doc(empty)
+		return
+	}
+
+	if t.Kind == idl.TypeEnum {
+		var values []string
+		for _, f := range t.Fields {
+			values = append(values, w.text(f.Name))
+		}
+		w.list(values)
+		return
+	}
+
+	var rows [][]string
+	for _, f := range t.Fields {
+		rows = append(rows, []string{w.text(f.Name), writeType(w, midl, f.Type)})
+	}
+	w.table([]string{"Name", "Type"}, rows)
+}
+
+// generateDoc renders the reference page of an interface.
+func generateDoc(midl *idl.IDL, useHTML bool) []byte {
+	w := newWriter(useHTML)
+
+	w.heading(1, "", midl.Name)
+	w.doc(midl.Doc)
+
+	var contents []string
+	if len(midl.Methods) > 0 {
+		contents = append(contents, w.link("#methods", "Methods"))
+	}
+	if len(midl.Errors) > 0 {
+		contents = append(contents, w.link("#errors", "Errors"))
+	}
+	if len(midl.Aliases) > 0 {
+		contents = append(contents, w.link("#types", "Types"))
+	}
+	w.list(contents)
+
+	if len(midl.Methods) > 0 {
+		w.heading(2, "methods", "Methods")
+		for _, m := range midl.Methods {
+			w.heading(3, "method-"+m.Name, m.Name)
+			w.doc(m.Doc)
+			w.code("method " + m.Name + m.In.String() + " -> " + m.Out.String())
+			w.heading(4, "", "Input")
+			writeFields(w, midl, m.In, "No parameters.")
+			w.heading(4, "", "Output")
+			writeFields(w, midl, m.Out, "No parameters.")
+		}
+	}
+
+	if len(midl.Errors) > 0 {
+		w.heading(2, "errors", "Errors")
+		for _, e := range midl.Errors {
+			w.heading(3, "error-"+e.Name, e.Name)
+			w.doc(e.Doc)
+			writeFields(w, midl, e.Type, "No parameters.")
+		}
+	}
+
+	if len(midl.Aliases) > 0 {
+		w.heading(2, "types", "Types")
+		for _, a := range midl.Aliases {
+			w.heading(3, "type-"+a.Name, a.Name)
+			w.doc(a.Doc)
+			w.code("type " + a.Name + " " + a.Type.String())
+			writeFields(w, midl, a.Type, "No fields.")
+		}
+	}
+
+	return finish(w, midl.Name)
+}
+
+// generateIndex renders a page linking to the reference pages of all
+// interfaces of a service.
+func generateIndex(info *serviceInfo, useHTML bool) []byte {
+	w := newWriter(useHTML)
+
+	ext := ".md"
+	if useHTML {
+		ext = ".html"
+	}
+
+	title := info.product
+	if title == "" {
+		title = "Interfaces"
+	}
+	w.heading(1, "", title)
+
+	var rows [][]string
+	for _, v := range [][2]string{
+		{"Vendor", info.vendor},
+		{"Product", info.product},
+		{"Version", info.version},
+		{"URL", info.url},
+	} {
+		if v[1] != "" {
+			rows = append(rows, []string{v[0], w.text(v[1])})
+		}
+	}
+	if len(rows) > 0 {
+		w.table([]string{"", ""}, rows)
+	}
+
+	w.heading(2, "", "Interfaces")
+	var items []string
+	for _, name := range info.interfaces {
+		items = append(items, w.link(name+ext, w.text(name)))
+	}
+	w.list(items)
+
+	return finish(w, title)
+}
+
+// writeDocs writes the reference pages of the interfaces and an index page
+// linking to them to the output directory.
+func writeDocs(output string, info *serviceInfo, idls []*idl.IDL, useHTML bool) error {
+	ext := ".md"
+	if useHTML {
+		ext = ".html"
+	}
+
+	err := os.MkdirAll(output, 0755)
+	if err != nil {
+		return fmt.Errorf("creating directory '%s': %v", output, err)
+	}
+
+	info.interfaces = nil
+	for _, midl := range idls {
+		info.interfaces = append(info.interfaces, midl.Name)
+		filename := filepath.Join(output, midl.Name+ext)
+		err := ioutil.WriteFile(filename, generateDoc(midl, useHTML), 0644)
+		if err != nil {
+			return fmt.Errorf("writing file '%s': %v", filename, err)
+		}
+	}
+
+	filename := filepath.Join(output, "index"+ext)
+	err = ioutil.WriteFile(filename, generateIndex(info, useHTML), 0644)
+	if err != nil {
+		return fmt.Errorf("writing file '%s': %v", filename, err)
+	}
+	return nil
+}
+
+// fetchDescriptions retrieves the service information and the descriptions
+// of all interfaces implemented by the service at the given address.
+func fetchDescriptions(ctx context.Context, address string) (*serviceInfo, []string, error) {
+	c, err := varlink.NewConnection(ctx, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c.Close()
+
+	var info serviceInfo
+	err = c.GetInfo(ctx, &info.vendor, &info.product, &info.version, &info.url, &info.interfaces)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	descriptions := make([]string, 0, len(info.interfaces))
+	for _, name := range info.interfaces {
+		description, err := c.GetInterfaceDescription(ctx, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("interface '%s': %v", name, err)
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	return &info, descriptions, nil
+}
+
+func main() {
+	var address string
+	var output string
+	var useHTML bool
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <file>...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] -varlink <address>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&address, "varlink", "", "Generate documentation for the service at the varlink address")
+	flag.StringVar(&output, "o", "", "Output directory, the current directory for more than one interface")
+	flag.BoolVar(&useHTML, "html", false, "Generate HTML instead of Markdown")
+	flag.Parse()
+
+	var info *serviceInfo
+	var descriptions []string
+
+	if address != "" {
+		if flag.NArg() != 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		var err error
+		info, descriptions, err = fetchDescriptions(context.Background(), address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error retrieving interfaces from '%s': %s\n", address, err)
+			os.Exit(1)
+		}
+	} else {
+		if flag.NArg() == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		info = &serviceInfo{}
+		for _, varlinkFile := range flag.Args() {
+			file, err := ioutil.ReadFile(varlinkFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading file '%s': %s\n", varlinkFile, err)
+				os.Exit(1)
+			}
+			descriptions = append(descriptions, string(file))
+		}
+	}
+
+	var idls []*idl.IDL
+	for _, description := range descriptions {
+		midl, err := idl.New(strings.TrimRight(description, "\n"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing interface: %s\n", err)
+			os.Exit(1)
+		}
+		idls = append(idls, midl)
+	}
+
+	// A single interface file is written to the standard output, the
+	// interfaces of a service and multiple files to a directory
+	if output == "" {
+		if address == "" && len(idls) == 1 {
+			os.Stdout.Write(generateDoc(idls[0], useHTML))
+			return
+		}
+		output = "."
+	}
+
+	if err := writeDocs(output, info, idls, useHTML); err != nil {
+		fmt.Fprintf(os.Stderr, "Error %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/varlink/go/varlink"
 )
 
 func expect(t *testing.T, expected string, returned string) {
@@ -13,6 +24,24 @@ func expect(t *testing.T, expected string, returned string) {
 	}
 }
 
+// fset and sourceImporter are shared by all type checks, so the imported
+// packages are only loaded once.
+var fset = token.NewFileSet()
+var sourceImporter = importer.ForCompiler(fset, "source", nil)
+
+// typeCheck fails the test if the generated source does not compile.
+func typeCheck(t *testing.T, source []byte) {
+	t.Helper()
+	f, err := parser.ParseFile(fset, "generated.go", source, 0)
+	if err != nil {
+		t.Fatalf("Error parsing generated source: %v\n%s", err, source)
+	}
+	conf := types.Config{Importer: sourceImporter}
+	if _, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("Error checking generated source: %v\n%s", err, source)
+	}
+}
+
 func TestIDLParser(t *testing.T) {
 	pkgname, b, err := generateTemplate(`
 # Interface to jump a spacecraft to another point in space. The 
@@ -77,7 +106,7 @@ method Foo(interface: string) -> (ret: (go: string, switch: bool, more: (t:bool,
 method TestMap(map: [string]string) -> (map: [string](i: int, val: string))
 method TestSet(set: [string]()) -> (set: [string]())
 method TestObject(object: object) -> (object: object)
-	`)
+	`, options{})
 
 	if err != nil {
 		t.Fatalf("Error parsing %v", err)
@@ -88,3 +117,311 @@ method TestObject(object: object) -> (object: object)
 	}
 	// FIXME: compare b.String() against expected output
 }
+
+func TestNamedTypes(t *testing.T) {
+	_, b, err := generateTemplate(`
+interface org.example.named
+
+type State (idle, busy)
+
+type Drive (
+  state: (on, off),
+  engines: ?[](id: int, state: State)
+)
+
+method Get(filter: (state: State, limit: int)) -> (drives: [string](name: string, drive: Drive))
+
+error Failed (reason: (broken, missing))
+`, options{})
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	source := string(b)
+
+	for _, decl := range []string{
+		"type State string",
+		"State_Idle State = \"idle\"",
+		"func (e State) Valid() bool {",
+		"func (e *State) UnmarshalJSON(data []byte) error {",
+		"type Drive_State string",
+		"Drive_State_On  Drive_State = \"on\"",
+		"type Drive_Engines struct {",
+		"Engines *[]Drive_Engines `json:\"engines,omitempty\"`",
+		"type Get_In_Filter struct {",
+		"type Get_Out_Drives struct {",
+		"type Failed_Reason string",
+		"Reason Failed_Reason `json:\"reason\"`",
+		"func (e Failed) Is(target error) bool {",
+		"var (\n\tErrFailed = &Failed{}\n)",
+		"varlink.RegisterError(\"org.example.named.Failed\", &Failed{})",
+		"Call(ctx context.Context, c *varlink.Connection, filter Get_In_Filter) (map[string]Get_Out_Drives, error)",
+		"in.Filter = filter",
+		"return out.Drives, err",
+		"func (c *VarlinkCall) StreamGet(ctx context.Context, drives map[string]Get_Out_Drives) error {",
+		"return c.Finish(ctx, &out)",
+		"func (m Get_methods) Oneway(ctx context.Context, c *varlink.Connection, filter Get_In_Filter) error {",
+	} {
+		if !strings.Contains(source, decl) {
+			t.Fatalf("Generated source does not contain `%s`:\n%s", decl, source)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, name := range [][3]string{
+		{"client_id", "ClientID", "clientID"},
+		{"url", "URL", "url"},
+		{"id_url", "IDURL", "idURL"},
+		{"nullable_array_struct", "NullableArrayStruct", "nullableArrayStruct"},
+		{"myType", "MyType", "myType"},
+		{"HTTPServer", "HTTPServer", "httpServer"},
+		{"uuid2", "Uuid2", "uuid2"},
+		{"Ab", "Ab", "ab"},
+		{"type", "Type", "type_"},
+		{"string", "String", "string_"},
+		{"ctx", "Ctx", "ctx_"},
+		{"err", "Err", "err_"},
+	} {
+		expect(t, name[1], goName(name[0]))
+		expect(t, name[2], localName(name[0]))
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	description := `
+interface org.example.names
+
+type Names (client_id: string, clientId: string, url: ?string)
+
+method Resolve(url: string, type: string, err: string) -> (id: int)
+`
+	_, b, err := generateTemplate(description, options{})
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	typeCheck(t, b)
+	source := string(b)
+
+	for _, decl := range []string{
+		"ClientID  string  `json:\"client_id\"`",
+		"ClientID_ string  `json:\"clientId\"`",
+		"URL       *string `json:\"url,omitempty\"`",
+		"Call(ctx context.Context, c *varlink.Connection, url string, type_ string, err_ string) (int64, error)",
+		"in.Err = err_",
+		"ID int64 `json:\"id\"`",
+	} {
+		if !strings.Contains(source, decl) {
+			t.Fatalf("Generated source does not contain `%s`:\n%s", decl, source)
+		}
+	}
+
+	_, b, err = generateTemplate(description, options{names: "title"})
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	typeCheck(t, b)
+	source = string(b)
+
+	for _, decl := range []string{
+		"Client_id string  `json:\"client_id\"`",
+		"ClientId  string  `json:\"clientId\"`",
+		"Url       *string `json:\"url,omitempty\"`",
+	} {
+		if !strings.Contains(source, decl) {
+			t.Fatalf("Generated source does not contain `%s`:\n%s", decl, source)
+		}
+	}
+
+	if _, _, err := generateTemplate(description, options{names: "snake"}); err == nil {
+		t.Fatal("Unknown naming scheme accepted")
+	}
+}
+
+func TestEnumNames(t *testing.T) {
+	_, b, err := generateTemplate(`
+interface org.example.enum
+
+type E (a_b, aB)
+
+method Get() -> (e: E)
+`, options{})
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	typeCheck(t, b)
+	source := string(b)
+
+	for _, decl := range []string{
+		"E_AB  E = \"a_b\"",
+		"E_AB_ E = \"aB\"",
+		"case E_AB, E_AB_:",
+	} {
+		if !strings.Contains(source, decl) {
+			t.Fatalf("Generated source does not contain `%s`:\n%s", decl, source)
+		}
+	}
+}
+
+func TestNestedTypeNames(t *testing.T) {
+	_, b, err := generateTemplate(`
+interface org.example.nested
+
+type T (b_c: (x: int), bC: (y: int))
+
+method Get(b_c: (a, b), bC: (c, d)) -> (t: T)
+`, options{})
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	typeCheck(t, b)
+	source := string(b)
+
+	for _, decl := range []string{
+		"type T_BC struct",
+		"type T_BC_ struct",
+		"type Get_In_BC string",
+		"type Get_In_BC_ string",
+	} {
+		if !strings.Contains(source, decl) {
+			t.Fatalf("Generated source does not contain `%s`:\n%s", decl, source)
+		}
+	}
+}
+
+const checkDescription = `
+interface org.example.check
+
+method Ping(ping: string) -> (pong: string)
+`
+
+func TestOptions(t *testing.T) {
+	pkgname, b, err := generateTemplate(checkDescription, options{pkgname: "check", tags: "linux && !android"})
+	if err != nil {
+		t.Fatalf("Error parsing %v", err)
+	}
+	expect(t, "check", pkgname)
+
+	source := string(b)
+	if !strings.HasPrefix(source, "//go:build linux && !android\n\n// Code generated") {
+		t.Fatalf("Generated source has no build constraint:\n%s", source)
+	}
+	if !strings.Contains(source, "\npackage check\n") {
+		t.Fatalf("Generated source has the wrong package name:\n%s", source)
+	}
+}
+
+func TestGenerateFile(t *testing.T) {
+	dir := t.TempDir()
+	varlinkFile := filepath.Join(dir, "org.example.check.varlink")
+	if err := ioutil.WriteFile(varlinkFile, []byte(checkDescription), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "out")
+	if err := os.Mkdir(output, 0755); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{output: output, file: "check.go"}
+
+	opts.check = true
+	if generateFile(varlinkFile, opts) {
+		t.Fatal("Missing file is up to date")
+	}
+
+	opts.check = false
+	if !generateFile(varlinkFile, opts) {
+		t.Fatal("generateFile() failed")
+	}
+	if _, err := os.Stat(filepath.Join(output, "check.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.check = true
+	if !generateFile(varlinkFile, opts) {
+		t.Fatal("Generated file is not up to date")
+	}
+
+	if err := ioutil.WriteFile(varlinkFile, []byte(checkDescription+"method Quit() -> ()\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if generateFile(varlinkFile, opts) {
+		t.Fatal("Changed file is up to date")
+	}
+}
+
+// testInterface is an interface served to test fetching descriptions.
+type testInterface struct {
+	name        string
+	description string
+	address     string // the address returned by Resolve
+}
+
+func (s *testInterface) VarlinkDispatch(ctx context.Context, call varlink.Call, methodname string) error {
+	if methodname == "Resolve" {
+		return call.Reply(ctx, &struct {
+			Address string `json:"address"`
+		}{s.address})
+	}
+	return call.ReplyMethodNotImplemented(ctx, methodname)
+}
+
+func (s *testInterface) VarlinkGetName() string {
+	return s.name
+}
+
+func (s *testInterface) VarlinkGetDescription() string {
+	return s.description
+}
+
+func TestGenerateRemote(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	address := "unix:" + filepath.Join(dir, "service")
+	resolverAddress := "unix:" + filepath.Join(dir, "resolver")
+
+	for _, iface := range []*testInterface{
+		{name: "org.example.check", description: strings.TrimPrefix(checkDescription, "\n")},
+		{name: "org.varlink.resolver", description: "interface org.varlink.resolver\n\nmethod Resolve(interface: string) -> (address: string)", address: address},
+	} {
+		service, err := varlink.NewService("Varlink", "Test", "1", "https://github.com/varlink/go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.RegisterInterface(iface); err != nil {
+			t.Fatal(err)
+		}
+		serviceAddress := address
+		if iface.name == "org.varlink.resolver" {
+			serviceAddress = resolverAddress
+		}
+		if err := service.Bind(ctx, serviceAddress); err != nil {
+			t.Fatal(err)
+		}
+		go service.DoListen(ctx, 0)
+		defer service.Shutdown()
+	}
+
+	for _, r := range []remote{{address: address}, {resolver: resolverAddress}} {
+		output := t.TempDir()
+		opts := options{output: output}
+
+		if !generateRemote(ctx, r, "org.example.check", opts) {
+			t.Fatal("generateRemote() failed")
+		}
+		description, err := ioutil.ReadFile(filepath.Join(output, "org.example.check.varlink"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(t, strings.TrimPrefix(checkDescription, "\n"), string(description))
+		if _, err := os.Stat(filepath.Join(output, "orgexamplecheck.go")); err != nil {
+			t.Fatal(err)
+		}
+
+		opts.check = true
+		if !generateRemote(ctx, r, "org.example.check", opts) {
+			t.Fatal("Generated files are not up to date")
+		}
+	}
+}
@@ -2,17 +2,202 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"flag"
 	"fmt"
 	"go/format"
 	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
+	"unicode"
 
+	"github.com/varlink/go/varlink"
 	"github.com/varlink/go/varlink/idl"
 )
 
-func writeType(b *bytes.Buffer, t *idl.Type, json bool, ident int) {
+// commonInitialisms are the words written in upper case in Go identifiers.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// reservedNames can not be used for parameters of generated functions,
+// because they are Go keywords, predeclared identifiers, imported packages
+// or local variables of the generated code.
+var reservedNames = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true,
+	"for": true, "func": true, "go": true, "goto": true, "if": true,
+	"import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true,
+	"switch": true, "type": true, "var": true,
+
+	"any": true, "append": true, "bool": true, "byte": true, "cap": true,
+	"clear": true, "close": true, "comparable": true, "complex": true,
+	"complex64": true, "complex128": true, "copy": true, "delete": true,
+	"error": true, "false": true, "float32": true, "float64": true,
+	"imag": true, "int": true, "int8": true, "int16": true, "int32": true,
+	"int64": true, "iota": true, "len": true, "make": true, "max": true,
+	"min": true, "new": true, "nil": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true, "rune": true,
+	"string": true, "true": true, "uint": true, "uint8": true, "uint16": true,
+	"uint32": true, "uint64": true, "uintptr": true,
+
+	"context": true, "fmt": true, "json": true, "sync": true, "varlink": true,
+
+	"c": true, "call": true, "conn": true, "ctx": true, "e": true, "err": true,
+	"f": true, "flags": true, "in": true, "m": true, "methodname": true,
+	"out": true, "receive": true, "s": true,
+}
+
+// splitWords splits a varlink name at underscores and at the case changes of
+// camel case.
+func splitWords(name string) []string {
+	var words []string
+	for _, part := range strings.Split(name, "_") {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			// "fooBar" splits before "B", "HTTPServer" before "S"
+			if unicode.IsUpper(runes[i]) && (lower || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			words = append(words, string(runes[start:]))
+		}
+	}
+	return words
+}
+
+// goName converts a varlink name to an exported Go identifier, "client_id"
+// becomes "ClientID".
+func goName(name string) string {
+	var s string
+	for _, word := range splitWords(name) {
+		upper := strings.ToUpper(word)
+		if commonInitialisms[upper] {
+			s += upper
+			continue
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		s += string(runes)
+	}
+	if s == "" {
+		return "X"
+	}
+	return s
+}
+
+// localName converts a varlink name to an unexported Go identifier, which is
+// not a reserved name, "client_id" becomes "clientID".
+func localName(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return "x"
+	}
+	s := strings.ToLower(words[0])
+	if len(words) > 1 {
+		s += goName(strings.Join(words[1:], "_"))
+	}
+	if reservedNames[s] {
+		s += "_"
+	}
+	return s
+}
+
+// uniqueNames appends underscores to names, which are already taken by a
+// previous name.
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	for i, name := range names {
+		for seen[name] {
+			name += "_"
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// paramNames returns the names of the Go parameters for fields.
+func paramNames(fields []idl.TypeField) []string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = localName(field.Name)
+	}
+	return uniqueNames(names)
+}
+
+// generator writes the Go code for an interface description.
+type generator struct {
+	// names are the Go type names of the structs and enums, which are not
+	// written inline
+	names map[*idl.Type]string
+
+	// titleNames selects the naming scheme of previous versions, which
+	// only capitalizes the first letter of a varlink name
+	titleNames bool
+}
+
+// exportedName returns the Go name for a field, enum value or the suffix of
+// a nested type name.
+func (g *generator) exportedName(name string) string {
+	if g.titleNames {
+		return strings.Title(name)
+	}
+	return goName(name)
+}
+
+// fieldNames returns the names of the Go struct fields for a struct, or the
+// suffixes of the constants for an enum.
+func (g *generator) fieldNames(t *idl.Type) []string {
+	names := make([]string, len(t.Fields))
+	for i, field := range t.Fields {
+		names[i] = g.exportedName(field.Name)
+	}
+	return uniqueNames(names)
+}
+
+// nameTypes names the anonymous structs and enums nested in a type after the
+// path of fields leading to them.
+func (g *generator) nameTypes(t *idl.Type, name string) {
+	switch t.Kind {
+	case idl.TypeArray, idl.TypeMap, idl.TypeMaybe:
+		g.nameTypes(t.ElementType, name)
+
+	case idl.TypeStruct, idl.TypeEnum:
+		// Empty structs are written as struct{}
+		if t.Kind == idl.TypeStruct && len(t.Fields) == 0 {
+			return
+		}
+		if _, ok := g.names[t]; !ok {
+			g.names[t] = name
+		}
+		for i, fieldName := range g.fieldNames(t) {
+			if field := t.Fields[i]; field.Type != nil {
+				g.nameTypes(field.Type, name+"_"+fieldName)
+			}
+		}
+	}
+}
+
+func (g *generator) writeType(b *bytes.Buffer, t *idl.Type, ident int) {
+	if name, ok := g.names[t]; ok {
+		b.WriteString(name)
+		return
+	}
+
 	switch t.Kind {
 	case idl.TypeBool:
 		b.WriteString("bool")
@@ -23,7 +208,7 @@ func writeType(b *bytes.Buffer, t *idl.Type, json bool, ident int) {
 	case idl.TypeFloat:
 		b.WriteString("float64")
 
-	case idl.TypeString, idl.TypeEnum:
+	case idl.TypeString:
 		b.WriteString("string")
 
 	case idl.TypeObject:
@@ -31,15 +216,15 @@ func writeType(b *bytes.Buffer, t *idl.Type, json bool, ident int) {
 
 	case idl.TypeArray:
 		b.WriteString("[]")
-		writeType(b, t.ElementType, json, ident)
+		g.writeType(b, t.ElementType, ident)
 
 	case idl.TypeMap:
 		b.WriteString("map[string]")
-		writeType(b, t.ElementType, json, ident)
+		g.writeType(b, t.ElementType, ident)
 
 	case idl.TypeMaybe:
 		b.WriteString("*")
-		writeType(b, t.ElementType, json, ident)
+		g.writeType(b, t.ElementType, ident)
 
 	case idl.TypeAlias:
 		b.WriteString(t.Alias)
@@ -49,21 +234,19 @@ func writeType(b *bytes.Buffer, t *idl.Type, json bool, ident int) {
 			b.WriteString("struct{}")
 		} else {
 			b.WriteString("struct {\n")
-			for _, field := range t.Fields {
+			names := g.fieldNames(t)
+			for i, field := range t.Fields {
 				for i := 0; i < ident+1; i++ {
 					b.WriteString("\t")
 				}
 
-				b.WriteString(strings.Title(field.Name) + " ")
-				writeType(b, field.Type, json, ident+1)
-				if json {
-					b.WriteString(" `json:\"" + field.Name)
-					if field.Type.Kind == idl.TypeMaybe {
-						b.WriteString(",omitempty")
-					}
-					b.WriteString("\"`")
+				b.WriteString(names[i] + " ")
+				g.writeType(b, field.Type, ident+1)
+				b.WriteString(" `json:\"" + field.Name)
+				if field.Type.Kind == idl.TypeMaybe {
+					b.WriteString(",omitempty")
 				}
-				b.WriteString("\n")
+				b.WriteString("\"`\n")
 			}
 			for i := 0; i < ident; i++ {
 				b.WriteString("\t")
@@ -73,6 +256,142 @@ func writeType(b *bytes.Buffer, t *idl.Type, json bool, ident int) {
 	}
 }
 
+// writeDecl writes the declaration of a named struct or enum.
+func (g *generator) writeDecl(b *bytes.Buffer, name string, t *idl.Type) {
+	if t.Kind != idl.TypeEnum {
+		b.WriteString("type " + name + " ")
+		// Write the struct itself, not its name
+		delete(g.names, t)
+		g.writeType(b, t, 0)
+		g.names[t] = name
+		b.WriteString("\n\n")
+		return
+	}
+
+	values := g.fieldNames(t)
+	b.WriteString("type " + name + " string\n\n")
+	b.WriteString("const (\n")
+	for i, field := range t.Fields {
+		b.WriteString("\t" + name + "_" + values[i] + " " + name + " = \"" + field.Name + "\"\n")
+	}
+	b.WriteString(")\n\n")
+
+	b.WriteString("// Valid reports whether the value is one of the values of the enum.\n")
+	b.WriteString("func (e " + name + ") Valid() bool {\n")
+	b.WriteString("\tswitch e {\n")
+	b.WriteString("\tcase ")
+	for i, value := range values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name + "_" + value)
+	}
+	b.WriteString(":\n")
+	b.WriteString("\t\treturn true\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\treturn false\n")
+	b.WriteString("}\n\n")
+
+	b.WriteString("// UnmarshalJSON rejects strings which are not values of the enum.\n")
+	b.WriteString("func (e *" + name + ") UnmarshalJSON(data []byte) error {\n")
+	b.WriteString("\tvar s string\n")
+	b.WriteString("\tif err := json.Unmarshal(data, &s); err != nil {\n")
+	b.WriteString("\t\treturn err\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\tif !" + name + "(s).Valid() {\n")
+	b.WriteString("\t\treturn fmt.Errorf(\"invalid value %q for " + name + "\", s)\n")
+	b.WriteString("\t}\n")
+	b.WriteString("\t*e = " + name + "(s)\n")
+	b.WriteString("\treturn nil\n")
+	b.WriteString("}\n\n")
+}
+
+// writeNestedDecls writes the declarations of the named types nested in a
+// type.
+func (g *generator) writeNestedDecls(b *bytes.Buffer, t *idl.Type) {
+	idl.Inspect(t, func(n idl.Node) bool {
+		if nested, ok := n.(*idl.Type); ok && nested != t {
+			if name, ok := g.names[nested]; ok {
+				g.writeDecl(b, name, nested)
+			}
+		}
+		return true
+	})
+}
+
+// writeParams writes the parameters for fields, each preceded by a comma.
+func (g *generator) writeParams(b *bytes.Buffer, fields []idl.TypeField) {
+	names := paramNames(fields)
+	for i, field := range fields {
+		b.WriteString(", " + names[i] + " ")
+		g.writeType(b, field.Type, 1)
+	}
+}
+
+// writeArgs writes the parameters for fields as arguments, each preceded by
+// a comma.
+func writeArgs(b *bytes.Buffer, fields []idl.TypeField) {
+	for _, name := range paramNames(fields) {
+		b.WriteString(", " + name)
+	}
+}
+
+// writeResultTypes writes the types of the fields of a struct as results,
+// each followed by a comma.
+func (g *generator) writeResultTypes(b *bytes.Buffer, t *idl.Type) {
+	for _, field := range t.Fields {
+		g.writeType(b, field.Type, 1)
+		b.WriteString(", ")
+	}
+}
+
+// writeResults writes the fields of the local variable out as results, each
+// followed by a comma.
+func (g *generator) writeResults(b *bytes.Buffer, t *idl.Type) {
+	for _, name := range g.fieldNames(t) {
+		b.WriteString("out." + name + ", ")
+	}
+}
+
+// writeAssignments assigns the parameters for the fields of a struct to the
+// fields of a local variable.
+func (g *generator) writeAssignments(b *bytes.Buffer, variable string, t *idl.Type) {
+	params := paramNames(t.Fields)
+	for i, name := range g.fieldNames(t) {
+		b.WriteString("\t" + variable + "." + name + " = " + params[i] + "\n")
+	}
+}
+
+// writeInParameters declares the local variable in with the method
+// parameters, if there are any.
+func (g *generator) writeInParameters(b *bytes.Buffer, t *idl.Type) {
+	if len(t.Fields) == 0 {
+		return
+	}
+	b.WriteString("\tvar in ")
+	g.writeType(b, t, 1)
+	b.WriteString("\n")
+	g.writeAssignments(b, "in", t)
+}
+
+// inArg returns the argument for the method parameters declared by
+// writeInParameters.
+func inArg(t *idl.Type) string {
+	if len(t.Fields) == 0 {
+		return "nil"
+	}
+	return "in"
+}
+
+// writeClientSignature writes the parameters and results of a client method.
+func (g *generator) writeClientSignature(b *bytes.Buffer, m *idl.Method) {
+	b.WriteString("(ctx context.Context")
+	g.writeParams(b, m.In.Fields)
+	b.WriteString(") (")
+	g.writeResultTypes(b, m.Out)
+	b.WriteString("error)")
+}
+
 func writeDocString(b *bytes.Buffer, s string) {
 	if s == "" {
 		return
@@ -83,7 +402,17 @@ func writeDocString(b *bytes.Buffer, s string) {
 	b.WriteString("\n")
 }
 
-func generateTemplate(description string) (string, []byte, error) {
+// options control the generated code and where it is written to.
+type options struct {
+	pkgname string // package name, derived from the interface name if empty
+	tags    string // build constraint of the generated file
+	output  string // output directory
+	file    string // name of the generated file
+	names   string // naming scheme of Go identifiers, "go" or "title"
+	check   bool   // only check whether the generated file is up to date
+}
+
+func generateTemplate(description string, opts options) (string, []byte, error) {
 	description = strings.TrimRight(description, "\n")
 
 	midl, err := idl.New(description)
@@ -91,9 +420,42 @@ func generateTemplate(description string) (string, []byte, error) {
 		return "", nil, err
 	}
 
-	pkgname := strings.ToLower(strings.Replace(midl.Name, ".", "", -1))
+	pkgname := opts.pkgname
+	if pkgname == "" {
+		pkgname = strings.ToLower(strings.Replace(midl.Name, ".", "", -1))
+	}
+
+	g := &generator{names: make(map[*idl.Type]string)}
+	switch opts.names {
+	case "", "go":
+	case "title":
+		g.titleNames = true
+	default:
+		return "", nil, fmt.Errorf("unknown naming scheme '%s'", opts.names)
+	}
+	for _, a := range midl.Aliases {
+		g.names[a.Type] = a.Name
+	}
+	for _, a := range midl.Aliases {
+		g.nameTypes(a.Type, a.Name)
+	}
+	for _, e := range midl.Errors {
+		g.nameTypes(e.Type, e.Name)
+		g.names[e.Type] = e.Name
+	}
+	for _, m := range midl.Methods {
+		for i, fieldName := range g.fieldNames(m.In) {
+			g.nameTypes(m.In.Fields[i].Type, m.Name+"_In_"+fieldName)
+		}
+		for i, fieldName := range g.fieldNames(m.Out) {
+			g.nameTypes(m.Out.Fields[i].Type, m.Name+"_Out_"+fieldName)
+		}
+	}
 
 	var b bytes.Buffer
+	if opts.tags != "" {
+		b.WriteString("//go:build " + opts.tags + "\n\n")
+	}
 	b.WriteString("// Code generated by github.com/varlink/go/cmd/varlink-go-interface-generator, DO NOT EDIT.\n\n")
 
 	writeDocString(&b, midl.Doc)
@@ -104,29 +466,33 @@ func generateTemplate(description string) (string, []byte, error) {
 
 	for _, a := range midl.Aliases {
 		writeDocString(&b, a.Doc)
-		b.WriteString("type " + a.Name + " ")
-		writeType(&b, a.Type, true, 0)
-		b.WriteString("\n\n")
+		g.writeDecl(&b, a.Name, a.Type)
+		g.writeNestedDecls(&b, a.Type)
+	}
+
+	for _, m := range midl.Methods {
+		g.writeNestedDecls(&b, m.In)
+		g.writeNestedDecls(&b, m.Out)
 	}
 
 	for _, a := range midl.Errors {
 		writeDocString(&b, a.Doc)
-		b.WriteString("type " + a.Name + " ")
-		writeType(&b, a.Type, true, 0)
-		b.WriteString("\nfunc (e " + a.Name + ") Error() string {\n")
+		g.writeDecl(&b, a.Name, a.Type)
+		b.WriteString("func (e " + a.Name + ") Error() string {\n")
 		b.WriteString("\ts := \"" + midl.Name + "." + a.Name + "\"\n")
 		if len(a.Type.Fields) > 0 {
+			names := g.fieldNames(a.Type)
 			b.WriteString("\ts += fmt.Sprintf(\"(")
-			for i, f := range a.Type.Fields {
-				b.WriteString(strings.Title(f.Name) + ": %v")
-				if i != len(a.Type.Fields)-1 {
+			for i, name := range names {
+				b.WriteString(name + ": %v")
+				if i != len(names)-1 {
 					b.WriteString(", ")
 				}
 			}
 			b.WriteString(")\", ")
-			for i, f := range a.Type.Fields {
-				b.WriteString("e." + strings.Title(f.Name))
-				if i != len(a.Type.Fields)-1 {
+			for i, name := range names {
+				b.WriteString("e." + name)
+				if i != len(names)-1 {
 					b.WriteString(", ")
 				}
 			}
@@ -134,28 +500,40 @@ func generateTemplate(description string) (string, []byte, error) {
 		}
 		b.WriteString("\treturn s")
 		b.WriteString("}\n\n")
+		b.WriteString("// Is reports whether target is the " + a.Name + " error, for use with errors.Is.\n")
+		b.WriteString("func (e " + a.Name + ") Is(target error) bool {\n" +
+			"\tswitch target.(type) {\n" +
+			"\tcase " + a.Name + ", *" + a.Name + ":\n" +
+			"\t\treturn true\n" +
+			"\t}\n" +
+			"\treturn false\n" +
+			"}\n\n")
+		g.writeNestedDecls(&b, a.Type)
 	}
 
-	b.WriteString("func Dispatch_Error(err error) error {\n")
-	b.WriteString("\tif e, ok := err.(*varlink.Error); ok {\n")
-	b.WriteString("\t\tswitch e.Name {\n")
-	for _, a := range midl.Errors {
-		b.WriteString("\t\tcase \"" + midl.Name + "." + a.Name + "\":\n")
-		b.WriteString("\t\t\terrorRawParameters := e.Parameters.(*json.RawMessage)\n")
-		b.WriteString("\t\t\tif errorRawParameters == nil {\n")
-		b.WriteString("\t\t\t\treturn e\n")
-		b.WriteString("\t\t\t}\n")
-		b.WriteString("\t\t\tvar param " + a.Name + "\n")
-		b.WriteString("\t\t\terr := json.Unmarshal(*errorRawParameters, &param)\n")
-		b.WriteString("\t\t\tif err != nil {\n")
-		b.WriteString("\t\t\t\treturn e\n")
-		b.WriteString("\t\t\t}\n")
-		b.WriteString("\t\t\treturn &param\n")
-	}
-	b.WriteString("\t\t}\n")
-	b.WriteString("\t}\n")
-	b.WriteString("\treturn err\n")
-	b.WriteString("}\n\n")
+	if len(midl.Errors) > 0 {
+		b.WriteString("// Sentinel values of the errors, for use with errors.Is\n")
+		b.WriteString("var (\n")
+		for _, a := range midl.Errors {
+			b.WriteString("\tErr" + a.Name + " = &" + a.Name + "{}\n")
+		}
+		b.WriteString(")\n\n")
+
+		b.WriteString("func init() {\n")
+		for _, a := range midl.Errors {
+			b.WriteString("\tvarlink.RegisterError(\"" + midl.Name + "." + a.Name + "\", &" + a.Name + "{})\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("// Dispatch_Error returns a varlink.Error as the Go type registered for its\n" +
+		"// name. Errors received by a varlink.Connection are already dispatched.\n")
+	b.WriteString("func Dispatch_Error(err error) error {\n" +
+		"\tif e, ok := err.(*varlink.Error); ok {\n" +
+		"\t\treturn e.DispatchError()\n" +
+		"\t}\n" +
+		"\treturn err\n" +
+		"}\n\n")
 
 	b.WriteString("// Generated client method calls\n\n")
 
@@ -165,168 +543,180 @@ func generateTemplate(description string) (string, []byte, error) {
 		b.WriteString("func " + m.Name + "() " + m.Name + "_methods { return " + m.Name + "_methods{} }\n\n")
 
 		b.WriteString("func (m " + m.Name + "_methods) Call(ctx context.Context, c *varlink.Connection")
-		for _, field := range m.In.Fields {
-			b.WriteString(", " + field.Name + "_in_ ")
-			writeType(&b, field.Type, false, 1)
-		}
+		g.writeParams(&b, m.In.Fields)
 		b.WriteString(") (")
-		for _, field := range m.Out.Fields {
-			b.WriteString(field.Name + "_out_ ")
-			writeType(&b, field.Type, false, 1)
-			b.WriteString(", ")
-		}
-		b.WriteString("err_ error) {\n")
-		b.WriteString("receive, err_ := m.Send(ctx, c, 0")
-		for _, field := range m.In.Fields {
-			b.WriteString(", " + field.Name + "_in_ ")
+		g.writeResultTypes(&b, m.Out)
+		b.WriteString("error) {\n")
+		if len(m.Out.Fields) > 0 {
+			b.WriteString("\tvar out ")
+			g.writeType(&b, m.Out, 1)
+			b.WriteString("\n")
 		}
+		b.WriteString("\treceive, err := m.Send(ctx, c, 0")
+		writeArgs(&b, m.In.Fields)
 		b.WriteString(")\n")
-		b.WriteString("if err_ != nil {\n" +
-			"\treturn\n" +
-			"}\n")
-		b.WriteString("\t")
-		for _, field := range m.Out.Fields {
-			b.WriteString(field.Name + "_out_ ")
-			b.WriteString(", ")
+		b.WriteString("\tif err == nil {\n")
+		b.WriteString("\t\t")
+		for _, name := range g.fieldNames(m.Out) {
+			b.WriteString("out." + name + ", ")
 		}
-		b.WriteString("_, err_ = receive(ctx)\n")
-		b.WriteString("\treturn\n" +
-			"}\n\n")
+		b.WriteString("_, err = receive(ctx)\n")
+		b.WriteString("\t}\n")
+		b.WriteString("\treturn ")
+		g.writeResults(&b, m.Out)
+		b.WriteString("err\n")
+		b.WriteString("}\n\n")
 
 		b.WriteString("func (m " + m.Name + "_methods) Send(ctx context.Context, c *varlink.Connection, flags uint64")
-		for _, field := range m.In.Fields {
-			b.WriteString(", " + field.Name + "_in_ ")
-			writeType(&b, field.Type, false, 1)
-		}
+		g.writeParams(&b, m.In.Fields)
 		b.WriteString(") (func(ctx context.Context) (")
-		for _, field := range m.Out.Fields {
-			writeType(&b, field.Type, false, 1)
-			b.WriteString(", ")
-		}
+		g.writeResultTypes(&b, m.Out)
 		b.WriteString("uint64, error), error) {\n")
-		if len(m.In.Fields) > 0 {
-			b.WriteString("\tvar in ")
-			writeType(&b, m.In, true, 1)
-			b.WriteString("\n")
-			for _, field := range m.In.Fields {
-				switch field.Type.Kind {
-				case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-					b.WriteString("\tin." + strings.Title(field.Name) + " = ")
-					writeType(&b, field.Type, true, 1)
-					b.WriteString("(" + field.Name + "_in_)\n")
-
-				default:
-					b.WriteString("\tin." + strings.Title(field.Name) + " = " + field.Name + "_in_\n")
-				}
-			}
-			b.WriteString("\treceive, err := c.Send(ctx, \"" + midl.Name + "." + m.Name + "\", in, flags)\n")
-		} else {
-			b.WriteString("\treceive, err := c.Send(ctx, \"" + midl.Name + "." + m.Name + "\", nil, flags)\n")
-		}
+		g.writeInParameters(&b, m.In)
+		b.WriteString("\treceive, err := c.Send(ctx, \"" + midl.Name + "." + m.Name + "\", " + inArg(m.In) + ", flags)\n")
 		b.WriteString("\tif err != nil {\n" +
 			"\t\treturn nil, err\n" +
 			"\t}\n")
-		b.WriteString("\treturn func(context.Context) (")
-		for _, field := range m.Out.Fields {
-			b.WriteString(field.Name + "_out_ ")
-			writeType(&b, field.Type, false, 3)
-			b.WriteString(", ")
-		}
-		b.WriteString("flags uint64, err error) {\n")
+		b.WriteString("\treturn func(ctx context.Context) (")
+		g.writeResultTypes(&b, m.Out)
+		b.WriteString("uint64, error) {\n")
 		if len(m.Out.Fields) > 0 {
 			b.WriteString("\t\tvar out ")
-			writeType(&b, m.Out, true, 2)
+			g.writeType(&b, m.Out, 2)
 			b.WriteString("\n")
-			b.WriteString("\t\tflags, err = receive(ctx, &out)\n")
+			b.WriteString("\t\tflags, err := receive(ctx, &out)\n")
 		} else {
-			b.WriteString("\t\tflags, err = receive(ctx, nil)\n")
-		}
-		b.WriteString("\t\tif err != nil {\n" +
-			"\t\t\terr = Dispatch_Error(err)\n" +
-			"\t\t\treturn\n" +
-			"\t\t}\n")
-		for _, field := range m.Out.Fields {
-			b.WriteString("\t\t" + field.Name + "_out_ = ")
-			switch field.Type.Kind {
-			case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-				writeType(&b, field.Type, false, 2)
-				b.WriteString("(out." + strings.Title(field.Name) + ")\n")
-
-			default:
-				b.WriteString("out." + strings.Title(field.Name) + "\n")
-			}
+			b.WriteString("\t\tflags, err := receive(ctx, nil)\n")
 		}
-		b.WriteString("\t\treturn\n" +
-			"\t}, nil\n")
+		b.WriteString("\t\treturn ")
+		g.writeResults(&b, m.Out)
+		b.WriteString("flags, err\n")
+		b.WriteString("\t}, nil\n")
+		b.WriteString("}\n\n")
+
+		b.WriteString("// Oneway calls the method without waiting for a reply.\n")
+		b.WriteString("func (m " + m.Name + "_methods) Oneway(ctx context.Context, c *varlink.Connection")
+		g.writeParams(&b, m.In.Fields)
+		b.WriteString(") error {\n")
+		g.writeInParameters(&b, m.In)
+		b.WriteString("\t_, err := c.Send(ctx, \"" + midl.Name + "." + m.Name + "\", " + inArg(m.In) + ", varlink.Oneway)\n")
+		b.WriteString("\treturn err\n")
 		b.WriteString("}\n\n")
 
 		b.WriteString("func (m " + m.Name + "_methods) Upgrade(ctx context.Context, c *varlink.Connection")
-		for _, field := range m.In.Fields {
-			b.WriteString(", " + field.Name + "_in_ ")
-			writeType(&b, field.Type, false, 1)
-		}
+		g.writeParams(&b, m.In.Fields)
 		b.WriteString(") (func(ctx context.Context) (")
-		for _, field := range m.Out.Fields {
-			b.WriteString(field.Name + "_out_ ")
-			writeType(&b, field.Type, false, 1)
-			b.WriteString(", ")
-		}
-		b.WriteString("flags uint64, conn varlink.ReadWriterContext, err_ error), error) {\n")
-		if len(m.In.Fields) > 0 {
-			b.WriteString("\tvar in ")
-			writeType(&b, m.In, true, 1)
+		g.writeResultTypes(&b, m.Out)
+		b.WriteString("uint64, varlink.ReadWriterContext, error), error) {\n")
+		g.writeInParameters(&b, m.In)
+		b.WriteString("\treceive, err := c.Upgrade(ctx, \"" + midl.Name + "." + m.Name + "\", " + inArg(m.In) + ")\n")
+		b.WriteString("\tif err != nil {\n" +
+			"\t\treturn nil, err\n" +
+			"\t}\n")
+		b.WriteString("\treturn func(ctx context.Context) (")
+		g.writeResultTypes(&b, m.Out)
+		b.WriteString("uint64, varlink.ReadWriterContext, error) {\n")
+		if len(m.Out.Fields) > 0 {
+			b.WriteString("\t\tvar out ")
+			g.writeType(&b, m.Out, 2)
 			b.WriteString("\n")
-			for _, field := range m.In.Fields {
-				switch field.Type.Kind {
-				case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-					b.WriteString("\tin." + strings.Title(field.Name) + " = ")
-					writeType(&b, field.Type, true, 1)
-					b.WriteString("(" + field.Name + "_in_)\n")
-
-				default:
-					b.WriteString("\tin." + strings.Title(field.Name) + " = " + field.Name + "_in_\n")
-				}
-			}
-			b.WriteString("\treceive, err := c.Upgrade(ctx, \"" + midl.Name + "." + m.Name + "\", in)\n")
+			b.WriteString("\t\tflags, conn, err := receive(ctx, &out)\n")
 		} else {
-			b.WriteString("\treceive, err := c.Upgrade(ctx, \"" + midl.Name + "." + m.Name + "\", nil)\n")
+			b.WriteString("\t\tflags, conn, err := receive(ctx, nil)\n")
 		}
-		b.WriteString("if err != nil {\n" +
-			"\treturn nil, err\n" +
-			"}\n")
+		b.WriteString("\t\treturn ")
+		g.writeResults(&b, m.Out)
+		b.WriteString("flags, conn, err\n")
+		b.WriteString("\t}, nil\n")
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("// Generated client interface with all methods\n\n")
+
+	b.WriteString("// VarlinkClient calls the methods of the interface, see VarlinkNewClient and\n" +
+		"// VarlinkClientMock.\n")
+	b.WriteString("type VarlinkClient interface {\n")
+	for _, m := range midl.Methods {
+		writeDocString(&b, m.Doc)
 		b.WriteString("\t")
-		b.WriteString("\treturn func(context.Context) (")
-		for _, field := range m.Out.Fields {
-			b.WriteString(field.Name + "_out_ ")
-			writeType(&b, field.Type, false, 3)
-			b.WriteString(", ")
-		}
-		b.WriteString("flags uint64, conn varlink.ReadWriterContext, err error) {\n")
+		b.WriteString(m.Name)
+		g.writeClientSignature(&b, m)
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("type varlinkClient struct {\n" +
+		"\tc *varlink.Connection\n" +
+		"}\n\n")
+
+	b.WriteString("// VarlinkNewClient returns a VarlinkClient calling the methods over a connection.\n")
+	b.WriteString("func VarlinkNewClient(c *varlink.Connection) VarlinkClient {\n" +
+		"\treturn &varlinkClient{c}\n" +
+		"}\n\n")
+
+	for _, m := range midl.Methods {
+		b.WriteString("func (c *varlinkClient) ")
+		b.WriteString(m.Name)
+		g.writeClientSignature(&b, m)
+		b.WriteString(" {\n")
+		b.WriteString("\treturn " + m.Name + "().Call(ctx, c.c")
+		writeArgs(&b, m.In.Fields)
+		b.WriteString(")\n")
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString("// Generated client test double\n\n")
+
+	b.WriteString("// VarlinkClientMockCall is a method call recorded by VarlinkClientMock.\n")
+	b.WriteString("type VarlinkClientMockCall struct {\n" +
+		"\tMethod     string\n" +
+		"\tParameters []interface{}\n" +
+		"}\n\n")
+
+	b.WriteString("// VarlinkClientMock is a VarlinkClient for tests. Its methods record the call\n" +
+		"// and call the function of the same name, or fail with MethodNotImplemented\n" +
+		"// if the function is nil. It is safe for concurrent use.\n")
+	b.WriteString("type VarlinkClientMock struct {\n")
+	for _, m := range midl.Methods {
+		b.WriteString("\t" + m.Name + "Func func")
+		g.writeClientSignature(&b, m)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n\tmutex sync.Mutex\n" +
+		"\tcalls []VarlinkClientMockCall\n" +
+		"}\n\n")
+
+	b.WriteString("// VarlinkCalls returns the recorded method calls in the order they were made.\n")
+	b.WriteString("func (m *VarlinkClientMock) VarlinkCalls() []VarlinkClientMockCall {\n" +
+		"\tm.mutex.Lock()\n" +
+		"\tdefer m.mutex.Unlock()\n" +
+		"\treturn append([]VarlinkClientMockCall(nil), m.calls...)\n" +
+		"}\n\n")
+
+	for _, m := range midl.Methods {
+		b.WriteString("func (m *VarlinkClientMock) ")
+		b.WriteString(m.Name)
+		g.writeClientSignature(&b, m)
+		b.WriteString(" {\n")
+		b.WriteString("\tm.mutex.Lock()\n")
+		b.WriteString("\tm.calls = append(m.calls, VarlinkClientMockCall{Method: \"" + m.Name + "\", Parameters: []interface{}{")
+		b.WriteString(strings.Join(paramNames(m.In.Fields), ", "))
+		b.WriteString("}})\n")
+		b.WriteString("\tf := m." + m.Name + "Func\n")
+		b.WriteString("\tm.mutex.Unlock()\n")
+		b.WriteString("\tif f == nil {\n")
 		if len(m.Out.Fields) > 0 {
 			b.WriteString("\t\tvar out ")
-			writeType(&b, m.Out, true, 2)
+			g.writeType(&b, m.Out, 2)
 			b.WriteString("\n")
-			b.WriteString("\t\tflags, conn, err = receive(ctx, &out)\n")
-		} else {
-			b.WriteString("\t\tflags, conn, err = receive(ctx, nil)\n")
 		}
-		b.WriteString("\t\tif err != nil {\n" +
-			"\t\t\terr = Dispatch_Error(err)\n" +
-			"\t\t\treturn\n" +
-			"\t\t}\n")
-		for _, field := range m.Out.Fields {
-			b.WriteString("\t\t" + field.Name + "_out_ = ")
-			switch field.Type.Kind {
-			case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-				writeType(&b, field.Type, false, 2)
-				b.WriteString("(out." + strings.Title(field.Name) + ")\n")
-
-			default:
-				b.WriteString("out." + strings.Title(field.Name) + "\n")
-			}
-		}
-		b.WriteString("\t\treturn\n" +
-			"\t}, nil\n")
+		b.WriteString("\t\treturn ")
+		g.writeResults(&b, m.Out)
+		b.WriteString("&varlink.MethodNotImplemented{Method: \"" + midl.Name + "." + m.Name + "\"}\n")
+		b.WriteString("\t}\n")
+		b.WriteString("\treturn f(ctx")
+		writeArgs(&b, m.In.Fields)
+		b.WriteString(")\n")
 		b.WriteString("}\n\n")
 	}
 
@@ -335,10 +725,7 @@ func generateTemplate(description string) (string, []byte, error) {
 	b.WriteString("type " + pkgname + "Interface interface {\n")
 	for _, m := range midl.Methods {
 		b.WriteString("\t" + m.Name + "(ctx context.Context, c VarlinkCall")
-		for _, field := range m.In.Fields {
-			b.WriteString(", " + field.Name + "_ ")
-			writeType(&b, field.Type, false, 1)
-		}
+		g.writeParams(&b, m.In.Fields)
 		b.WriteString(") error\n")
 	}
 	b.WriteString("}\n\n")
@@ -351,29 +738,11 @@ func generateTemplate(description string) (string, []byte, error) {
 
 	for _, e := range midl.Errors {
 		writeDocString(&b, e.Doc)
-		b.WriteString("func (c *VarlinkCall) Reply" + e.Name + "(ctx context.Context, ")
-		for i, field := range e.Type.Fields {
-			if i > 0 {
-				b.WriteString(", ")
-			}
-			b.WriteString(field.Name + "_ ")
-			writeType(&b, field.Type, false, 1)
-		}
+		b.WriteString("func (c *VarlinkCall) Reply" + e.Name + "(ctx context.Context")
+		g.writeParams(&b, e.Type.Fields)
 		b.WriteString(") error {\n")
 		b.WriteString("\tvar out " + e.Name + "\n")
-		if len(e.Type.Fields) > 0 {
-			for _, field := range e.Type.Fields {
-				switch field.Type.Kind {
-				case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-					b.WriteString("\tout." + strings.Title(field.Name) + " = ")
-					writeType(&b, field.Type, true, 1)
-					b.WriteString("(" + field.Name + "_)\n")
-
-				default:
-					b.WriteString("\tout." + strings.Title(field.Name) + " = " + field.Name + "_\n")
-				}
-			}
-		}
+		g.writeAssignments(&b, "out", e.Type)
 		b.WriteString("\treturn c.ReplyError(ctx, \"" + midl.Name + "." + e.Name + "\", &out)\n")
 		b.WriteString("}\n\n")
 	}
@@ -381,35 +750,28 @@ func generateTemplate(description string) (string, []byte, error) {
 	b.WriteString("// Generated reply methods for all varlink methods\n\n")
 
 	for _, m := range midl.Methods {
-		b.WriteString("func (c *VarlinkCall) Reply" + m.Name + "(ctx context.Context, ")
-		for i, field := range m.Out.Fields {
-			if i > 0 {
-				b.WriteString(", ")
-			}
-			b.WriteString(field.Name + "_ ")
-			writeType(&b, field.Type, false, 1)
-		}
-		b.WriteString(") error {\n")
-		if len(m.Out.Fields) > 0 {
-			b.WriteString("\tvar out ")
-			writeType(&b, m.Out, true, 1)
-			b.WriteString("\n")
-			for _, field := range m.Out.Fields {
-				switch field.Type.Kind {
-				case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-					b.WriteString("\tout." + strings.Title(field.Name) + " = ")
-					writeType(&b, field.Type, true, 1)
-					b.WriteString("(" + field.Name + "_)\n")
-
-				default:
-					b.WriteString("\tout." + strings.Title(field.Name) + " = " + field.Name + "_\n")
-				}
+		for _, reply := range []struct{ name, doc string }{
+			{"Reply", ""},
+			{"Stream", "// Stream" + m.Name + " sends a reply, which is followed by more replies. It fails\n" +
+				"// if the client did not set more.\n"},
+			{"Finish", "// Finish" + m.Name + " sends the final reply, which ends the replies sent with\n" +
+				"// Stream" + m.Name + ".\n"},
+		} {
+			b.WriteString(reply.doc)
+			b.WriteString("func (c *VarlinkCall) " + reply.name + m.Name + "(ctx context.Context")
+			g.writeParams(&b, m.Out.Fields)
+			b.WriteString(") error {\n")
+			if len(m.Out.Fields) > 0 {
+				b.WriteString("\tvar out ")
+				g.writeType(&b, m.Out, 1)
+				b.WriteString("\n")
+				g.writeAssignments(&b, "out", m.Out)
+				b.WriteString("\treturn c." + reply.name + "(ctx, &out)\n")
+			} else {
+				b.WriteString("\treturn c." + reply.name + "(ctx, nil)\n")
 			}
-			b.WriteString("\treturn c.Reply(ctx, &out)\n")
-		} else {
-			b.WriteString("\treturn c.Reply(ctx, nil)\n")
+			b.WriteString("}\n\n")
 		}
-		b.WriteString("}\n\n")
 	}
 
 	b.WriteString("// Generated dummy implementations for all varlink methods\n\n")
@@ -417,10 +779,7 @@ func generateTemplate(description string) (string, []byte, error) {
 	for _, m := range midl.Methods {
 		writeDocString(&b, m.Doc)
 		b.WriteString("func (s *VarlinkInterface) " + m.Name + "(ctx context.Context, c VarlinkCall")
-		for _, field := range m.In.Fields {
-			b.WriteString(", " + field.Name + "_ ")
-			writeType(&b, field.Type, false, 1)
-		}
+		g.writeParams(&b, m.In.Fields)
 		b.WriteString(") error {\n" +
 			"\treturn c.ReplyMethodNotImplemented(ctx, \"" + midl.Name + "." + m.Name + "\")\n" +
 			"}\n\n")
@@ -434,25 +793,15 @@ func generateTemplate(description string) (string, []byte, error) {
 		b.WriteString("\tcase \"" + m.Name + "\":\n")
 		if len(m.In.Fields) > 0 {
 			b.WriteString("\t\tvar in ")
-			writeType(&b, m.In, true, 2)
+			g.writeType(&b, m.In, 2)
 			b.WriteString("\n")
 			b.WriteString("\t\terr := call.GetParameters(&in)\n" +
 				"\t\tif err != nil {\n" +
 				"\t\t\treturn call.ReplyInvalidParameter(ctx, \"parameters\")\n" +
 				"\t\t}\n")
 			b.WriteString("\t\treturn s." + pkgname + "Interface." + m.Name + "(ctx, VarlinkCall{call}")
-			if len(m.In.Fields) > 0 {
-				for _, field := range m.In.Fields {
-					switch field.Type.Kind {
-					case idl.TypeStruct, idl.TypeArray, idl.TypeMap:
-						b.WriteString(", ")
-						writeType(&b, field.Type, false, 2)
-						b.WriteString("(in." + strings.Title(field.Name) + ")")
-
-					default:
-						b.WriteString(", in." + strings.Title(field.Name))
-					}
-				}
+			for _, name := range g.fieldNames(m.In) {
+				b.WriteString(", in." + name)
 			}
 			b.WriteString(")\n")
 		} else {
@@ -492,12 +841,15 @@ func generateTemplate(description string) (string, []byte, error) {
 	if strings.Contains(ret_string, "context.Context") {
 		imports = append(imports, "\"context\"")
 	}
-	if strings.Contains(ret_string, "json.RawMessage") {
+	if strings.Contains(ret_string, "json.RawMessage") || strings.Contains(ret_string, "json.Unmarshal") {
 		imports = append(imports, "\"encoding/json\"")
 	}
-	if strings.Contains(ret_string, "fmt.Sprintf") {
+	if strings.Contains(ret_string, "fmt.Sprintf") || strings.Contains(ret_string, "fmt.Errorf") {
 		imports = append(imports, "\"fmt\"")
 	}
+	if strings.Contains(ret_string, "sync.Mutex") {
+		imports = append(imports, "\"sync\"")
+	}
 	ret_string = strings.Replace(ret_string, "@IMPORTS@", fmt.Sprintf("import (\n%s\n)", strings.Join(imports, "\n\t")), 1)
 
 	pretty, err := format.Source([]byte(ret_string))
@@ -508,31 +860,201 @@ func generateTemplate(description string) (string, []byte, error) {
 	return pkgname, pretty, nil
 }
 
-func generateFile(varlinkFile string) {
-	file, err := ioutil.ReadFile(varlinkFile)
+// generateFile generates the Go code for an interface description file, "-"
+// reads it from stdin. It returns false if the generated file is not up to
+// date in check mode.
+func generateFile(varlinkFile string, opts options) bool {
+	var file []byte
+	var err error
+	if varlinkFile == "-" {
+		varlinkFile = "<stdin>"
+		file, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		file, err = ioutil.ReadFile(varlinkFile)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file '%s': %s\n", varlinkFile, err)
 		os.Exit(1)
 	}
 
-	pkgname, b, err := generateTemplate(string(file))
+	pkgname, b, err := generateTemplate(string(file), opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing file '%s': %s\n", varlinkFile, err)
 		os.Exit(1)
 	}
 
-	filename := path.Dir(varlinkFile) + "/" + pkgname + ".go"
-	err = ioutil.WriteFile(filename, b, 0660)
+	// Without an output location, generated code from stdin goes to stdout
+	if varlinkFile == "<stdin>" && opts.output == "" && opts.file == "" && !opts.check {
+		os.Stdout.Write(b)
+		return true
+	}
+
+	dir := opts.output
+	if dir == "" {
+		dir = filepath.Dir(varlinkFile)
+	}
+	name := opts.file
+	if name == "" {
+		name = pkgname + ".go"
+	}
+	filename := filepath.Join(dir, name)
+
+	if opts.check {
+		current, err := ioutil.ReadFile(filename)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error reading file '%s': %s\n", filename, err)
+			os.Exit(1)
+		}
+		if !bytes.Equal(current, b) {
+			fmt.Fprintf(os.Stderr, "File '%s' is not up to date with '%s'\n", filename, varlinkFile)
+			return false
+		}
+		return true
+	}
+
+	err = ioutil.WriteFile(filename, b, 0644)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing file '%s': %s\n", filename, err)
 		os.Exit(1)
 	}
+	return true
+}
+
+// remote selects the service to fetch interface descriptions from.
+type remote struct {
+	address  string // address of the service
+	resolver string // address of the resolver, which resolves the interface names
+	bridge   string // bridge command connecting to the service
+}
+
+// connect returns a connection to the service implementing an interface.
+func (r remote) connect(ctx context.Context, name string) (*varlink.Connection, error) {
+	switch {
+	case r.bridge != "":
+		return varlink.NewBridge(r.bridge)
+
+	case r.resolver != "":
+		resolver, err := varlink.NewResolver(ctx, r.resolver)
+		if err != nil {
+			return nil, err
+		}
+		defer resolver.Close()
+
+		address, err := resolver.Resolve(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return varlink.NewConnection(ctx, address)
+
+	default:
+		return varlink.NewConnection(ctx, r.address)
+	}
+}
+
+// fetchDescription returns the description of an interface from a service.
+func (r remote) fetchDescription(ctx context.Context, name string) (string, error) {
+	c, err := r.connect(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	return c.GetInterfaceDescription(ctx, name)
+}
+
+// generateRemote fetches the description of an interface from a service and
+// saves it as <interface>.varlink in the output directory, which keeps the
+// build reproducible, before generating the Go code from it. It returns false
+// if the saved description or the generated file is not up to date in check
+// mode.
+func generateRemote(ctx context.Context, r remote, name string, opts options) bool {
+	description, err := r.fetchDescription(ctx, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching interface '%s': %s\n", name, err)
+		os.Exit(1)
+	}
+	b := []byte(strings.TrimRight(description, "\n") + "\n")
+
+	dir := opts.output
+	if dir == "" {
+		dir = "."
+	}
+	varlinkFile := filepath.Join(dir, name+".varlink")
+
+	if opts.check {
+		current, err := ioutil.ReadFile(varlinkFile)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error reading file '%s': %s\n", varlinkFile, err)
+			os.Exit(1)
+		}
+		if !bytes.Equal(current, b) {
+			fmt.Fprintf(os.Stderr, "File '%s' is not up to date with interface '%s'\n", varlinkFile, name)
+			return false
+		}
+	} else {
+		err = ioutil.WriteFile(varlinkFile, b, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file '%s': %s\n", varlinkFile, err)
+			os.Exit(1)
+		}
+	}
+
+	return generateFile(varlinkFile, opts)
 }
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Printf("Usage: %s <file>\n", os.Args[0])
+	var opts options
+	var r remote
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <file>...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [options] -varlink <address> | -resolver <address> | -bridge <command> <interface>...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "A file named '-' is read from stdin. Interfaces fetched from a service are\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "saved as <interface>.varlink in the output directory.\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&opts.output, "o", "", "Output directory, instead of the directory of the input file")
+	flag.StringVar(&opts.pkgname, "package", "", "Package name, instead of the interface name without dots")
+	flag.StringVar(&opts.file, "file", "", "Name of the generated file, instead of <package>.go")
+	flag.StringVar(&opts.tags, "tags", "", "Build constraint expression of the generated file")
+	flag.StringVar(&opts.names, "names", "go", "Naming scheme of Go identifiers: 'go' converts snake_case to CamelCase with initialisms, 'title' only capitalizes the first letter")
+	flag.BoolVar(&opts.check, "check", false, "Check that the generated files are up to date, instead of writing them")
+	flag.StringVar(&r.address, "varlink", "", "Fetch the interfaces from the service at this address")
+	flag.StringVar(&r.resolver, "resolver", "", "Fetch the interfaces from the services found by the resolver at this address, e.g. "+varlink.ResolverAddress)
+	flag.StringVar(&r.bridge, "bridge", "", "Fetch the interfaces through this bridge command")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if flag.NArg() > 1 && (opts.pkgname != "" || opts.file != "") {
+		fmt.Fprintf(os.Stderr, "The -package and -file options require a single input file\n")
+		os.Exit(1)
+	}
+
+	sources := 0
+	for _, s := range []string{r.address, r.resolver, r.bridge} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		fmt.Fprintf(os.Stderr, "The -varlink, -resolver and -bridge options are mutually exclusive\n")
+		os.Exit(1)
+	}
+
+	upToDate := true
+	for _, arg := range flag.Args() {
+		if sources > 0 {
+			upToDate = generateRemote(context.Background(), r, arg, opts) && upToDate
+		} else {
+			upToDate = generateFile(arg, opts) && upToDate
+		}
+	}
+
+	if !upToDate {
 		os.Exit(1)
 	}
-	generateFile(os.Args[1])
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/varlink/go/varlink/idl"
+)
+
+func generateSchema(description string) ([]byte, error) {
+	midl, err := idl.New(strings.TrimRight(description, "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := midl.JSONSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+func main() {
+	if len(os.Args) < 2 || len(os.Args) > 3 {
+		fmt.Printf("Usage: %s <file> [<output file>]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	varlinkFile := os.Args[1]
+	file, err := ioutil.ReadFile(varlinkFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file '%s': %s\n", varlinkFile, err)
+		os.Exit(1)
+	}
+
+	b, err := generateSchema(string(file))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing file '%s': %s\n", varlinkFile, err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) == 2 {
+		os.Stdout.Write(b)
+		return
+	}
+
+	filename := os.Args[2]
+	err = ioutil.WriteFile(filename, b, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func expect(t *testing.T, expected string, returned string) {
+	if strings.Compare(returned, expected) != 0 {
+		t.Fatalf("Expected(%d): `%s`\nGot(%d): `%s`\n",
+			len(expected), expected,
+			len(returned), returned)
+	}
+}
+
+func allRules(t *testing.T) map[string]bool {
+	enabled, err := enabledRules("", "")
+	if err != nil {
+		t.Fatalf("enabledRules(): %v", err)
+	}
+	return enabled
+}
+
+func testLint(t *testing.T, enabled map[string]bool, description string) string {
+	diagnostics, err := lint(description, enabled, 3)
+	if err != nil {
+		t.Fatalf("lint(): %v", err)
+	}
+
+	var lines []string
+	for _, d := range diagnostics {
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", d.pos, d.message, d.rule))
+	}
+	return strings.Join(lines, "\n")
+}
+
+const lintDescription = `interface org.Example.lint
+
+type Unused (a: int)
+
+type FIELDS (
+  client_id: string,
+  clientName: string,
+  bad_Name: string
+)
+
+# Documented
+method Get(
+  config: (a: int, b: int, c: int, d: int),
+  small: (a: int, b: int),
+  value: Unused
+) -> ()
+
+method UNDOCUMENTED() -> ()
+
+error Failed ()
+`
+
+func TestPascalCase(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"Foo":       true,
+		"FooBar":    true,
+		"GetValue2": true,
+		"HTTPGet":   true,
+		"X":         true,
+		"":          false,
+		"foo":       false,
+		"fooBar":    false,
+		"Foo_bar":   false,
+		"FOO_bar":   false,
+		"Get_value": false,
+		"FOO":       false,
+		"FOO2":      false,
+	} {
+		if isPascalCase(name) != expected {
+			t.Fatalf("isPascalCase(%q) returned %v", name, !expected)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	expect(t, strings.Join([]string{
+		"1:11: interface name `org.Example.lint` is not a lowercase reverse-domain name (interface-name)",
+		"5:6: type name `FIELDS` is not PascalCase (member-name)",
+		"5:6: type `FIELDS` is unused (unused-type)",
+		"7:3: field name `clientName` is camelCase, but the interface uses snake_case (field-name)",
+		"8:3: field name `bad_Name` is neither snake_case nor camelCase (field-name)",
+		"13:11: anonymous struct with 4 fields in the input of `Get` should be a named type (anonymous-struct)",
+		"18:8: method name `UNDOCUMENTED` is not PascalCase (member-name)",
+		"18:8: method `UNDOCUMENTED` is undocumented (doc)",
+		"20:7: error `Failed` is undocumented (doc)",
+	}, "\n"), testLint(t, allRules(t), lintDescription))
+}
+
+func TestEnabledRules(t *testing.T) {
+	enabled, err := enabledRules("doc,unused-type", "unused-type")
+	if err != nil {
+		t.Fatalf("enabledRules(): %v", err)
+	}
+	expect(t, strings.Join([]string{
+		"18:8: method `UNDOCUMENTED` is undocumented (doc)",
+		"20:7: error `Failed` is undocumented (doc)",
+	}, "\n"), testLint(t, enabled, lintDescription))
+
+	if _, err := enabledRules("", "foo"); err == nil {
+		t.Fatal("enabledRules() accepted an unknown rule")
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	description := `# lint:file-ignore doc
+interface org.example.lint
+
+# lint:ignore unused-type,member-name
+type UNUSED (a: int)
+
+type OTHER (a: int) # lint:ignore unused-type
+
+method Get() -> ()
+`
+	expect(t, "7:6: type name `OTHER` is not PascalCase (member-name)",
+		testLint(t, allRules(t), description))
+
+	// Directives are no documentation, and only apply to the next line
+	description = `interface org.example.lint
+
+# lint:ignore member-name
+method GET() -> ()
+
+# lint:ignore unused-type
+
+type Unused (a: int)
+`
+	expect(t, strings.Join([]string{
+		"4:8: method `GET` is undocumented (doc)",
+		"8:6: type `Unused` is unused (unused-type)",
+	}, "\n"), testLint(t, allRules(t), description))
+}
+
+func TestValidInterface(t *testing.T) {
+	description := `# An interface without problems
+interface io.systemd.Example
+
+type State (idle, busy)
+
+# Get the state
+method Get(lastState: ?State, config: (a: int, b: int, c: int)) -> (state: State)
+
+# Failed to get the state
+error GetFailed (errorCode: int)
+`
+	expect(t, "", testLint(t, allRules(t), description))
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/varlink/go/varlink/idl"
+)
+
+// diagnostic is a problem found in an interface description.
+type diagnostic struct {
+	pos     idl.Position
+	rule    string
+	message string
+	// scope is the interface or member the problem belongs to, it is used
+	// to match lint:ignore directives
+	scope idl.Node
+}
+
+// linter runs the enabled rules over a parsed interface description.
+type linter struct {
+	midl        *idl.IDL
+	rule        string
+	maxFields   int
+	diagnostics []diagnostic
+}
+
+func (l *linter) report(scope idl.Node, pos idl.Position, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, diagnostic{
+		pos:     pos,
+		rule:    l.rule,
+		message: fmt.Sprintf(format, args...),
+		scope:   scope,
+	})
+}
+
+// rule checks an interface description for one kind of problem.
+type rule struct {
+	name  string
+	doc   string
+	check func(l *linter)
+}
+
+var rules = []rule{
+	{"interface-name", "interface names are reverse-domain names", checkInterfaceName},
+	{"member-name", "method, type and error names are PascalCase", checkMemberNames},
+	{"field-name", "field names are consistently snake_case or camelCase", checkFieldNames},
+	{"doc", "methods and errors are documented", checkDocs},
+	{"unused-type", "types are used by a method or an error", checkUnusedTypes},
+	{"anonymous-struct", "large anonymous structs in method input are named types", checkAnonymousStructs},
+}
+
+var domainLabel = regexp.MustCompile(`^[a-z0-9]+(-+[a-z0-9]+)*$`)
+
+func checkInterfaceName(l *linter) {
+	labels := strings.Split(l.midl.Name, ".")
+	// The last label may name the interface in PascalCase
+	for _, label := range labels[:len(labels)-1] {
+		if !domainLabel.MatchString(label) {
+			l.report(l.midl, l.midl.NamePos, "interface name `%s` is not a lowercase reverse-domain name", l.midl.Name)
+			return
+		}
+	}
+}
+
+var pascalCase = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// isPascalCase reports whether a name is PascalCase: it starts with an
+// uppercase letter, has no underscores and is not all uppercase, unless it
+// is a single letter.
+func isPascalCase(name string) bool {
+	return pascalCase.MatchString(name) && (len(name) == 1 || strings.ToUpper(name) != name)
+}
+
+func checkMemberNames(l *linter) {
+	for _, member := range l.midl.Members {
+		switch m := member.(type) {
+		case *idl.Alias:
+			if !isPascalCase(m.Name) {
+				l.report(m, m.NamePos, "type name `%s` is not PascalCase", m.Name)
+			}
+		case *idl.Method:
+			if !isPascalCase(m.Name) {
+				l.report(m, m.NamePos, "method name `%s` is not PascalCase", m.Name)
+			}
+		case *idl.Error:
+			if !isPascalCase(m.Name) {
+				l.report(m, m.NamePos, "error name `%s` is not PascalCase", m.Name)
+			}
+		}
+	}
+}
+
+var (
+	singleWord = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	snakeCase  = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)+$`)
+	camelCase  = regexp.MustCompile(`^[a-z][a-z0-9]*([A-Z][a-z0-9]*)+$`)
+)
+
+func fieldStyle(name string) string {
+	switch {
+	case singleWord.MatchString(name):
+		return ""
+	case snakeCase.MatchString(name):
+		return "snake_case"
+	case camelCase.MatchString(name):
+		return "camelCase"
+	}
+	return "invalid"
+}
+
+func checkFieldNames(l *linter) {
+	style := ""
+	for _, member := range l.midl.Members {
+		idl.Inspect(member, func(n idl.Node) bool {
+			f, ok := n.(*idl.TypeField)
+			// Enum values are no fields
+			if !ok || f.Type == nil {
+				return true
+			}
+			switch s := fieldStyle(f.Name); {
+			case s == "invalid":
+				l.report(member, f.Pos(), "field name `%s` is neither snake_case nor camelCase", f.Name)
+			case s == "":
+			case style == "":
+				style = s
+			case s != style:
+				l.report(member, f.Pos(), "field name `%s` is %s, but the interface uses %s", f.Name, s, style)
+			}
+			return true
+		})
+	}
+}
+
+var docDirective = regexp.MustCompile(`^\s*lint:(ignore|file-ignore)\b`)
+
+// isDocumented reports whether a doc comment has text besides lint
+// directives.
+func isDocumented(doc string) bool {
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.TrimSpace(line) != "" && !docDirective.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkDocs(l *linter) {
+	for _, m := range l.midl.Methods {
+		if !isDocumented(m.Doc) {
+			l.report(m, m.NamePos, "method `%s` is undocumented", m.Name)
+		}
+	}
+	for _, e := range l.midl.Errors {
+		if !isDocumented(e.Doc) {
+			l.report(e, e.NamePos, "error `%s` is undocumented", e.Name)
+		}
+	}
+}
+
+func checkUnusedTypes(l *linter) {
+	used := make(map[string]bool)
+
+	var markUsed func(n idl.Node)
+	markUsed = func(n idl.Node) {
+		idl.Inspect(n, func(n idl.Node) bool {
+			t, ok := n.(*idl.Type)
+			if !ok || t.Kind != idl.TypeAlias || used[t.Alias] {
+				return true
+			}
+			used[t.Alias] = true
+			if a := l.midl.LookupType(t.Alias); a != nil {
+				markUsed(a)
+			}
+			return true
+		})
+	}
+
+	for _, m := range l.midl.Methods {
+		markUsed(m)
+	}
+	for _, e := range l.midl.Errors {
+		markUsed(e)
+	}
+
+	for _, a := range l.midl.Aliases {
+		if !used[a.Name] {
+			l.report(a, a.NamePos, "type `%s` is unused", a.Name)
+		}
+	}
+}
+
+func checkAnonymousStructs(l *linter) {
+	for _, m := range l.midl.Methods {
+		for _, f := range m.In.Fields {
+			idl.Inspect(f.Type, func(n idl.Node) bool {
+				t, ok := n.(*idl.Type)
+				if ok && t.Kind == idl.TypeStruct && len(t.Fields) > l.maxFields {
+					l.report(m, t.Pos(), "anonymous struct with %d fields in the input of `%s` should be a named type", len(t.Fields), m.Name)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// directive is a lint:ignore or lint:file-ignore comment.
+type directive struct {
+	line  int
+	file  bool
+	rules []string
+}
+
+var directiveRegexp = regexp.MustCompile(`#\s*lint:(ignore|file-ignore)\s+([a-z,-]+)`)
+
+// parseDirectives returns the lint directives in the comments of a description.
+func parseDirectives(description string) []directive {
+	var directives []directive
+	for i, line := range strings.Split(description, "\n") {
+		m := directiveRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		directives = append(directives, directive{
+			line:  i + 1,
+			file:  m[1] == "file-ignore",
+			rules: strings.Split(m[2], ","),
+		})
+	}
+	return directives
+}
+
+func (d *directive) matches(rule string) bool {
+	for _, r := range d.rules {
+		if r == rule || r == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressed reports whether a directive applies to a diagnostic. A
+// lint:ignore directive applies to the interface or member starting on the
+// same line or on the first line following the directive.
+func suppressed(directives []directive, diag *diagnostic) bool {
+	for _, d := range directives {
+		if !d.matches(diag.rule) {
+			continue
+		}
+		if d.file {
+			return true
+		}
+		line := diag.scope.Pos().Line
+		if line == d.line || line == d.line+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// lint checks an interface description with the enabled rules and returns
+// the diagnostics which are not suppressed by directives, in the order of
+// the description.
+func lint(description string, enabled map[string]bool, maxFields int) ([]diagnostic, error) {
+	midl, err := idl.New(description)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{midl: midl, maxFields: maxFields}
+	for _, r := range rules {
+		if enabled[r.name] {
+			l.rule = r.name
+			r.check(l)
+		}
+	}
+
+	directives := parseDirectives(description)
+	var diagnostics []diagnostic
+	for i := range l.diagnostics {
+		if !suppressed(directives, &l.diagnostics[i]) {
+			diagnostics = append(diagnostics, l.diagnostics[i])
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].pos.Offset < diagnostics[j].pos.Offset
+	})
+	return diagnostics, nil
+}
+
+// enabledRules returns the set of enabled rules from the comma-separated
+// lists of the -enable and -disable flags.
+func enabledRules(enable string, disable string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	known := make(map[string]bool)
+	for _, r := range rules {
+		known[r.name] = true
+		enabled[r.name] = enable == ""
+	}
+
+	for _, list := range []struct {
+		names string
+		value bool
+	}{{enable, true}, {disable, false}} {
+		if list.names == "" {
+			continue
+		}
+		for _, name := range strings.Split(list.names, ",") {
+			if !known[name] {
+				return nil, fmt.Errorf("unknown rule '%s'", name)
+			}
+			enabled[name] = list.value
+		}
+	}
+
+	return enabled, nil
+}
+
+func main() {
+	var enable string
+	var disable string
+	var maxFields int
+	var list bool
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <file>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&enable, "enable", "", "Comma-separated list of rules to run, instead of all rules")
+	flag.StringVar(&disable, "disable", "", "Comma-separated list of rules to skip")
+	flag.IntVar(&maxFields, "max-fields", 5, "Maximum number of fields of an anonymous struct in method input")
+	flag.BoolVar(&list, "list", false, "List the available rules")
+	flag.Parse()
+
+	if list {
+		for _, r := range rules {
+			fmt.Printf("%-18s %s\n", r.name, r.doc)
+		}
+		return
+	}
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	enabled, err := enabledRules(enable, disable)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, varlinkFile := range flag.Args() {
+		file, err := ioutil.ReadFile(varlinkFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file '%s': %s\n", varlinkFile, err)
+			os.Exit(1)
+		}
+
+		diagnostics, err := lint(string(file), enabled, maxFields)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing file '%s': %s\n", varlinkFile, err)
+			os.Exit(1)
+		}
+
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s: %s (%s)\n", varlinkFile, d.pos, d.message, d.rule)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
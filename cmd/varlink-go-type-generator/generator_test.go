@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func expect(t *testing.T, expected string, returned string) {
+	if strings.Compare(returned, expected) != 0 {
+		t.Fatalf("Expected(%d): `%s`\nGot(%d): `%s`\n",
+			len(expected), expected,
+			len(returned), returned)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	expected, err := ioutil.ReadFile("testdata/drives/org.example.drives.varlink")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The output does not depend on map iteration order
+	for i := 0; i < 3; i++ {
+		descriptions, names, err := generateDescriptions("testdata/drives")
+		if err != nil {
+			t.Fatalf("generateDescriptions(): %v", err)
+		}
+		if len(names) != 1 || names[0] != "org.example.drives" {
+			t.Fatalf("Generated interfaces %v", names)
+		}
+		expect(t, string(expected), descriptions["org.example.drives"])
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	for _, test := range []struct {
+		source string
+		err    string
+	}{
+		{"type T struct{ C chan int `json:\"c\"` }",
+			"T.c: type chan int can not be represented in varlink"},
+		{"type T struct{ M map[int]string `json:\"m\"` }",
+			"T.m: map key type int is not a string"},
+		{"type T struct{ P **string `json:\"p\"` }",
+			"T.p: type **string is optional twice"},
+		{"type T struct{ Name string }",
+			"T.Name: 'Name' is not a valid varlink field name, set a json tag"},
+		{"type T struct{ E }\ntype E struct{}",
+			"T: embedded field E is not supported"},
+		{"type T struct{ U u `json:\"u\"` }\ntype u struct{}",
+			"T.u: type u is not exported"},
+		{"type T struct{ I interface{ M() } `json:\"i\"` }",
+			"T.i: type interface{M()} can not be represented in varlink"},
+	} {
+		dir := t.TempDir()
+		source := `package p
+
+import "context"
+
+//varlink:interface org.example.p
+type P interface {
+	Get(ctx context.Context) (t T, err error)
+}
+
+` + test.source + "\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := generateDescriptions(dir)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("generateDescriptions(`%s`) returned %v, expected %s", test.source, err, test.err)
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	for _, test := range []struct {
+		method string
+		err    string
+	}{
+		{"Get(ctx context.Context) (string, error)", "method Get: result 1 has no name"},
+		{"Get(context.Context, string) error", "method Get: parameter 2 has no name"},
+		{"Get(ctx context.Context) (s string)", "method Get: the last result is not an error"},
+		{"Get(ctx context.Context, s ...string) error", "method Get: variadic parameters are not supported"},
+		{"Get(ctx context.Context, Name string) error", "method Get parameter: 'Name' is not a valid varlink field name"},
+	} {
+		dir := t.TempDir()
+		source := `package p
+
+import "context"
+
+//varlink:interface org.example.p
+type P interface {
+	` + test.method + `
+}
+`
+		if err := ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := generateDescriptions(dir)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("generateDescriptions(`%s`) returned %v, expected %s", test.method, err, test.err)
+		}
+	}
+}
@@ -1,172 +1,688 @@
+// Varlink-go-type-generator converts Go interfaces to varlink interface
+// descriptions. A Go interface type annotated with
+//
+//	//varlink:interface org.example.name
+//
+// is converted to a varlink interface. Its methods become varlink methods:
+// an optional leading context.Context parameter is skipped, the other
+// parameters are the input fields, and the named results except a final
+// error are the output fields. Struct types annotated with
+//
+//	//varlink:error
+//
+// become the errors of all interfaces in the package. The named types used by
+// methods and errors are added as varlink types.
 package main
 
 import (
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/importer"
 	"go/parser"
 	"go/token"
 	"go/types"
-	"log"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/varlink/go/varlink/idl"
 )
 
-func IsBasicGoType(t types.Type, flag types.BasicInfo) bool {
-	switch u := t.(type) {
-	case *types.Basic:
-		if u.Info()&flag != 0 {
-			return true
+// field is a field of a varlink struct.
+type field struct {
+	name string
+	typ  string
+	doc  string
+}
+
+// member is a type, method or error of a varlink interface.
+type member struct {
+	pos     token.Pos
+	keyword string
+	name    string
+	doc     string
+	fields  []field // of a struct type or an error, or the method input
+	out     []field // of a method
+	enum    []string
+}
+
+// generator converts the annotated types of a Go package to varlink
+// interfaces.
+type generator struct {
+	pkg  *types.Package
+	docs map[token.Pos]string // doc comments by the position of the name
+
+	interfaces []*types.TypeName
+	names      map[*types.TypeName]string // interface names
+	errors     []*types.TypeName
+
+	enums map[*types.TypeName][]string // values of string constants by type
+	used  map[*types.TypeName]bool     // named types referenced by members
+	queue []*types.TypeName            // referenced types not yet converted
+}
+
+// directive returns the argument of a //varlink: directive in a comment.
+func directive(doc *ast.CommentGroup, name string) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, c := range doc.List {
+		if c.Text == "//varlink:"+name {
+			return "", true
+		}
+		if strings.HasPrefix(c.Text, "//varlink:"+name+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(c.Text, "//varlink:"+name+" ")), true
 		}
-		return false
-	case *types.Named:
-		return IsBasicGoType(u.Underlying(), flag)
 	}
-	return false
+	return "", false
 }
 
-func GoToVarlinkType(t types.Type) string {
-	if IsBasicGoType(t, types.IsBoolean) {
-		return "bool"
+// docText returns a comment as varlink documentation, without directives.
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
 	}
+	return strings.TrimRight(doc.Text(), "\n")
+}
 
-	if IsBasicGoType(t, types.IsInteger) {
-		return "int"
+// fset holds the positions of the parsed files, the source importer, which
+// caches the imported packages, adds to it.
+var (
+	fset           = token.NewFileSet()
+	sourceImporter = importer.ForCompiler(fset, "source", nil)
+)
+
+// newGenerator type checks the files of a package and collects its
+// annotations and doc comments.
+func newGenerator(files []*ast.File) (*generator, error) {
+	conf := types.Config{
+		Importer:    sourceImporter,
+		FakeImportC: true,
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := conf.Check(files[0].Name.Name, fset, files, info)
+	if err != nil {
+		return nil, err
 	}
 
-	if IsBasicGoType(t, types.IsFloat) {
-		return "float"
+	g := &generator{
+		pkg:   pkg,
+		docs:  make(map[token.Pos]string),
+		names: make(map[*types.TypeName]string),
+		enums: make(map[*types.TypeName][]string),
+		used:  make(map[*types.TypeName]bool),
 	}
 
-	if IsBasicGoType(t, types.IsString) {
-		return "string"
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				g.docs[ts.Name.Pos()] = docText(doc)
+				obj, _ := info.Defs[ts.Name].(*types.TypeName)
+				if obj == nil {
+					continue
+				}
+
+				if name, ok := directive(doc, "interface"); ok {
+					if _, ok := obj.Type().Underlying().(*types.Interface); !ok {
+						return nil, fmt.Errorf("%s: %s is annotated as varlink interface, but is not an interface", fset.Position(ts.Pos()), ts.Name.Name)
+					}
+					if name == "" {
+						return nil, fmt.Errorf("%s: the varlink interface of %s has no name", fset.Position(ts.Pos()), ts.Name.Name)
+					}
+					g.interfaces = append(g.interfaces, obj)
+					g.names[obj] = name
+				}
+				if _, ok := directive(doc, "error"); ok {
+					g.errors = append(g.errors, obj)
+				}
+			}
+		}
+
+		// Doc comments of struct fields and interface methods
+		ast.Inspect(file, func(n ast.Node) bool {
+			var list *ast.FieldList
+			switch t := n.(type) {
+			case *ast.StructType:
+				list = t.Fields
+			case *ast.InterfaceType:
+				list = t.Methods
+			default:
+				return true
+			}
+			for _, f := range list.List {
+				doc := f.Doc
+				if doc == nil {
+					doc = f.Comment
+				}
+				for _, name := range f.Names {
+					g.docs[name.Pos()] = docText(doc)
+				}
+			}
+			return true
+		})
+	}
+
+	// String constants of a named type are the values of an enum
+	scope := pkg.Scope()
+	var consts []*types.Const
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+	for _, c := range consts {
+		named, ok := c.Type().(*types.Named)
+		if !ok || named.Obj().Pkg() != pkg || c.Val().Kind() != constant.String {
+			continue
+		}
+		g.enums[named.Obj()] = append(g.enums[named.Obj()], constant.StringVal(c.Val()))
+	}
+
+	return g, nil
+}
+
+// isType reports whether t is the named type or type alias pkg.name.
+func isType(t types.Type, pkg string, name string) bool {
+	named, ok := t.(interface{ Obj() *types.TypeName })
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkg && named.Obj().Name() == name
+}
+
+// varlinkType returns the varlink type for a Go type, where describes the
+// location of the type in errors.
+func (g *generator) varlinkType(t types.Type, where string) (string, error) {
+	switch {
+	case isType(t, "encoding/json", "RawMessage"):
+		return "object", nil
+	case isType(t, "time", "Time"):
+		return "string", nil
 	}
 
 	switch u := t.(type) {
+	case *types.Named:
+		obj := u.Obj()
+
+		local := obj.Pkg() == g.pkg
+		_, isStruct := u.Underlying().(*types.Struct)
+		_, isEnum := g.enums[obj]
+		if local && (isStruct || isEnum) {
+			if !obj.Exported() {
+				return "", fmt.Errorf("%s: type %s is not exported, varlink type names start with an upper case letter", where, obj.Name())
+			}
+			if !g.used[obj] {
+				g.used[obj] = true
+				g.queue = append(g.queue, obj)
+			}
+			return obj.Name(), nil
+		}
+		if isStruct {
+			return "", fmt.Errorf("%s: struct type %s of another package is not supported", where, t)
+		}
+		if types.Implements(u, jsonMarshaler) || types.Implements(types.NewPointer(u), jsonMarshaler) {
+			return "", fmt.Errorf("%s: type %s implements json.Marshaler, its varlink type is unknown", where, t)
+		}
+		return g.varlinkType(u.Underlying(), where)
+
 	case *types.Basic:
-		return fmt.Sprintf("<<<%s>>>", t.String())
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "bool", nil
+		case u.Info()&types.IsInteger != 0:
+			return "int", nil
+		case u.Info()&types.IsFloat != 0:
+			return "float", nil
+		case u.Info()&types.IsString != 0:
+			return "string", nil
+		}
 
-	case *types.Named:
-		return u.Obj().Name()
+	case *types.Pointer:
+		s, err := g.varlinkType(u.Elem(), where)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(s, "?") {
+			return "", fmt.Errorf("%s: type %s is optional twice", where, t)
+		}
+		return "?" + s, nil
+
+	case *types.Slice:
+		// encoding/json writes byte slices as base64 strings
+		if b, ok := u.Elem().(*types.Basic); ok && b.Kind() == types.Byte {
+			return "string", nil
+		}
+		s, err := g.varlinkType(u.Elem(), where)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + s, nil
+
+	case *types.Array:
+		s, err := g.varlinkType(u.Elem(), where)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + s, nil
 
 	case *types.Map:
-		if IsBasicGoType(u.Key(), types.IsString) {
-			return fmt.Sprintf("[string]%s", GoToVarlinkType(u.Elem()))
-		} else {
-			return fmt.Sprintf("<<<%s>>>", u.String())
+		if b, ok := u.Key().Underlying().(*types.Basic); !ok || b.Info()&types.IsString == 0 {
+			return "", fmt.Errorf("%s: map key type %s is not a string", where, u.Key())
 		}
+		if st, ok := u.Elem().(*types.Struct); ok && st.NumFields() == 0 {
+			return "[string]()", nil
+		}
+		s, err := g.varlinkType(u.Elem(), where)
+		if err != nil {
+			return "", err
+		}
+		return "[string]" + s, nil
 
 	case *types.Interface:
 		if u.Empty() {
-			return "()"
+			return "object", nil
 		}
-		return fmt.Sprintf("<<<%s>>>", u.String())
 
-	case *types.Pointer:
-		return fmt.Sprintf("?%s", GoToVarlinkType(u.Elem()))
+	case *types.Struct:
+		fields, err := g.structFields(u, where)
+		if err != nil {
+			return "", err
+		}
+		return structType(fields, ""), nil
 
-	case *types.Array:
-		return fmt.Sprintf("[]%s", GoToVarlinkType(u.Elem()))
+	default:
+		// Type aliases
+		if alias, ok := t.(interface{ Rhs() types.Type }); ok {
+			return g.varlinkType(alias.Rhs(), where)
+		}
+		if t.Underlying() != t {
+			return g.varlinkType(t.Underlying(), where)
+		}
+	}
 
-	case *types.Slice:
-		return fmt.Sprintf("[]%s", GoToVarlinkType(u.Elem()))
+	return "", fmt.Errorf("%s: type %s can not be represented in varlink", where, t)
+}
 
-	case *types.Struct:
-		if u.NumFields() > 0 {
-			s := ""
-			for i := 0; i < u.NumFields(); i++ {
-				if i > 0 {
-					s += ",\n"
+var jsonMarshaler = types.NewInterfaceType([]*types.Func{
+	types.NewFunc(token.NoPos, nil, "MarshalJSON", types.NewSignatureType(nil, nil, nil, nil,
+		types.NewTuple(
+			types.NewVar(token.NoPos, nil, "", types.NewSlice(types.Typ[types.Byte])),
+			types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type()),
+		), false)),
+}, nil).Complete()
+
+// fieldName matches valid varlink field names.
+var fieldName = regexp.MustCompile(`^[a-z](_?[A-Za-z0-9])*$`)
+
+// checkFieldName fails for names, which are not valid varlink field names.
+func checkFieldName(name string, where string) error {
+	if !fieldName.MatchString(name) {
+		return fmt.Errorf("%s: '%s' is not a valid varlink field name", where, name)
+	}
+	return nil
+}
+
+// structFields returns the varlink fields of a struct, as encoding/json
+// writes them.
+func (g *generator) structFields(st *types.Struct, where string) ([]field, error) {
+	var fields []field
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		name := f.Name()
+		optional := false
+
+		tag := reflect.StructTag(st.Tag(i)).Get("json")
+		if tag == "-" {
+			continue
+		}
+		if tag != "" {
+			opts := strings.Split(tag, ",")
+			if opts[0] != "" {
+				name = opts[0]
+			}
+			for _, opt := range opts[1:] {
+				if opt == "omitempty" {
+					optional = true
 				}
-				s += fmt.Sprintf("\t%s: %s",
-					u.Field(i).Name(), GoToVarlinkType(u.Field(i).Type()))
 			}
+		}
 
-			return fmt.Sprintf("(\n%s\n)", s)
+		if f.Anonymous() {
+			return nil, fmt.Errorf("%s: embedded field %s is not supported", where, f.Name())
+		}
+		if !f.Exported() {
+			continue
 		}
-		return "()"
 
-	default:
-		return fmt.Sprintf("<<<%T %s>>>", t, u)
+		if err := checkFieldName(name, where+"."+f.Name()); err != nil {
+			return nil, fmt.Errorf("%s, set a json tag", err)
+		}
+		typ, err := g.varlinkType(f.Type(), where+"."+name)
+		if err != nil {
+			return nil, err
+		}
+		if optional && !strings.HasPrefix(typ, "?") {
+			typ = "?" + typ
+		}
+
+		fields = append(fields, field{name: name, typ: typ, doc: g.docs[f.Pos()]})
 	}
+	return fields, nil
 }
 
-func PrintDefsUses(name string, fset *token.FileSet, files []*ast.File) error {
-	conf := types.Config{
-		Importer:    importer.Default(),
-		FakeImportC: true,
+// structType returns a varlink struct. Structs with documented fields are
+// written on multiple lines with the indentation.
+func structType(fields []field, indent string) string {
+	multiline := false
+	for _, f := range fields {
+		if f.doc != "" {
+			multiline = true
+		}
 	}
 
-	info := &types.Info{
-		Defs: make(map[*ast.Ident]types.Object),
+	var b strings.Builder
+	b.WriteString("(")
+	for i, f := range fields {
+		if multiline {
+			b.WriteString("\n")
+			writeDoc(&b, f.doc, indent+"  ")
+			b.WriteString(indent + "  ")
+		} else if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.name + ": " + f.typ)
+		if multiline && i < len(fields)-1 {
+			b.WriteString(",")
+		}
 	}
+	if multiline {
+		b.WriteString("\n" + indent)
+	}
+	b.WriteString(")")
+	return b.String()
+}
 
-	_, err := conf.Check(name, fset, files, info)
-	if err != nil {
-		return err // type error
+// writeDoc writes documentation as varlink comment lines.
+func writeDoc(b *strings.Builder, doc string, indent string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			b.WriteString(indent + "#\n")
+		} else {
+			b.WriteString(indent + "# " + line + "\n")
+		}
+	}
+}
+
+// method converts a method of an annotated Go interface.
+func (g *generator) method(f *types.Func) (*member, error) {
+	where := "method " + f.Name()
+	if !f.Exported() {
+		return nil, fmt.Errorf("%s: method is not exported, varlink method names start with an upper case letter", where)
+	}
+	sig := f.Type().(*types.Signature)
+	if sig.Variadic() {
+		return nil, fmt.Errorf("%s: variadic parameters are not supported", where)
 	}
 
-	seen := map[string]interface{}{}
+	m := &member{pos: f.Pos(), keyword: "method", name: f.Name(), doc: g.docs[f.Pos()]}
 
-	for id, obj := range info.Defs {
-		if obj == nil {
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		if i == 0 && isType(p.Type(), "context", "Context") {
 			continue
 		}
+		if p.Name() == "" || p.Name() == "_" {
+			return nil, fmt.Errorf("%s: parameter %d has no name", where, i+1)
+		}
+		if err := checkFieldName(p.Name(), where+" parameter"); err != nil {
+			return nil, err
+		}
+		typ, err := g.varlinkType(p.Type(), where+" parameter "+p.Name())
+		if err != nil {
+			return nil, err
+		}
+		m.fields = append(m.fields, field{name: p.Name(), typ: typ})
+	}
 
-		if _, ok := seen[id.Name]; ok {
-			continue
+	results := sig.Results()
+	if results.Len() == 0 || !types.Identical(results.At(results.Len()-1).Type(), types.Universe.Lookup("error").Type()) {
+		return nil, fmt.Errorf("%s: the last result is not an error", where)
+	}
+	for i := 0; i < results.Len()-1; i++ {
+		r := results.At(i)
+		if r.Name() == "" || r.Name() == "_" {
+			return nil, fmt.Errorf("%s: result %d has no name", where, i+1)
+		}
+		if err := checkFieldName(r.Name(), where+" result"); err != nil {
+			return nil, err
+		}
+		typ, err := g.varlinkType(r.Type(), where+" result "+r.Name())
+		if err != nil {
+			return nil, err
 		}
+		m.out = append(m.out, field{name: r.Name(), typ: typ})
+	}
 
-		/*
-			if !obj.Exported() || obj.Pkg().Name() != name {
-				continue
-			}
-		*/
-		switch f := obj.Type().Underlying().(type) {
-		case *types.Struct:
-			if f.NumFields() > 0 {
-				fmt.Printf("type %s %s\n\n", id.Name, GoToVarlinkType(f))
+	return m, nil
+}
+
+// typeMember converts a named struct or enum type.
+func (g *generator) typeMember(obj *types.TypeName, keyword string) (*member, error) {
+	m := &member{pos: obj.Pos(), keyword: keyword, name: obj.Name(), doc: g.docs[obj.Pos()]}
+	if values, ok := g.enums[obj]; ok && keyword == "type" {
+		for _, value := range values {
+			if err := checkFieldName(value, "type "+obj.Name()); err != nil {
+				return nil, err
 			}
 		}
-		seen[id.Name] = nil
+		m.enum = values
+		return m, nil
 	}
 
-	return nil
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s %s: type is not a struct", keyword, obj.Name())
+	}
+	fields, err := g.structFields(st, obj.Name())
+	if err != nil {
+		return nil, err
+	}
+	m.fields = fields
+	return m, nil
 }
 
-func main() {
+// generate returns the varlink interface description of an annotated Go
+// interface. The types follow the order of their declaration, then the
+// methods in the order of the Go interface and the errors.
+func (g *generator) generate(iface *types.TypeName) (string, error) {
+	g.used = make(map[*types.TypeName]bool)
+	g.queue = nil
 
-	path := os.Args[1]
-	fs := token.NewFileSet()
+	it := iface.Type().Underlying().(*types.Interface)
+	if it.NumEmbeddeds() > 0 {
+		return "", fmt.Errorf("interface %s: embedded interfaces are not supported", iface.Name())
+	}
+	var funcs []*types.Func
+	for i := 0; i < it.NumExplicitMethods(); i++ {
+		funcs = append(funcs, it.ExplicitMethod(i))
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Pos() < funcs[j].Pos() })
 
-	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
-		pkgs, err := parser.ParseDir(fs, path, nil, 0)
+	var methods []*member
+	for _, f := range funcs {
+		m, err := g.method(f)
+		if err != nil {
+			return "", err
+		}
+		methods = append(methods, m)
+	}
+
+	var errors []*member
+	for _, obj := range g.errors {
+		m, err := g.typeMember(obj, "error")
 		if err != nil {
-			fmt.Printf("parsing dir '%s': %s", path, err)
+			return "", err
 		}
-		for name, pkg := range pkgs {
-			log.Println("Found package:", name)
+		errors = append(errors, m)
+	}
 
-			fset := make([]*ast.File, len(pkg.Files), len(pkg.Files))
-			idx := 0
-			for _, value := range pkg.Files {
-				fset[idx] = value
-				idx++
-			}
+	var aliases []*member
+	for len(g.queue) > 0 {
+		obj := g.queue[0]
+		g.queue = g.queue[1:]
+		m, err := g.typeMember(obj, "type")
+		if err != nil {
+			return "", err
+		}
+		aliases = append(aliases, m)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].pos < aliases[j].pos })
 
-			if err := PrintDefsUses(name, fs, fset); err != nil {
-				log.Print(err) // type error
-			}
+	var b strings.Builder
+	writeDoc(&b, g.docs[iface.Pos()], "")
+	b.WriteString("interface " + g.names[iface] + "\n")
+	for _, m := range append(append(aliases, methods...), errors...) {
+		b.WriteString("\n")
+		writeDoc(&b, m.doc, "")
+		switch {
+		case m.keyword == "method":
+			b.WriteString("method " + m.name + structType(m.fields, "") + " -> " + structType(m.out, "") + "\n")
+		case m.enum != nil:
+			b.WriteString("type " + m.name + " (" + strings.Join(m.enum, ", ") + ")\n")
+		default:
+			b.WriteString(m.keyword + " " + m.name + " " + structType(m.fields, "") + "\n")
 		}
-	} else {
+	}
+
+	description := b.String()
+	if _, err := idl.New(description); err != nil {
+		return "", fmt.Errorf("interface %s: the generated description is invalid: %s", g.names[iface], err)
+	}
+	return description, nil
+}
+
+// parse parses a Go file, or the non-test Go files of a package directory, in
+// the order of their names.
+func parse(path string) ([]*ast.File, error) {
+	filenames := []string{path}
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		filenames, err = filepath.Glob(filepath.Join(path, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(filenames)
+	}
+
+	var files []*ast.File
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") && len(filenames) > 1 {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files")
+	}
+	return files, nil
+}
+
+// generateDescriptions returns the varlink interface descriptions of the
+// annotated Go interfaces of a package, by interface name.
+func generateDescriptions(path string) (map[string]string, []string, error) {
+	files, err := parse(path)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		fset, err := parser.ParseFile(fs, path, nil, 0)
+	g, err := newGenerator(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(g.interfaces) == 0 {
+		return nil, nil, fmt.Errorf("no Go interface annotated with //varlink:interface")
+	}
 
+	descriptions := make(map[string]string)
+	var names []string
+	for _, iface := range g.interfaces {
+		description, err := g.generate(iface)
 		if err != nil {
-			fmt.Printf("parsing file '%s': %s", path, err)
+			return nil, nil, err
 		}
-		name := fset.Name.String()
-		if err := PrintDefsUses(name, fs, []*ast.File{fset}); err != nil {
-			log.Print(err) // type error
+		name := g.names[iface]
+		if _, ok := descriptions[name]; ok {
+			return nil, nil, fmt.Errorf("interface %s is annotated twice", name)
+		}
+		descriptions[name] = description
+		names = append(names, name)
+	}
+	return descriptions, names, nil
+}
+
+func main() {
+	var output string
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <package directory | file>\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Converts Go interfaces annotated with //varlink:interface <name> to varlink\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "interface descriptions, with the struct types annotated with //varlink:error\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "as errors.\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&output, "o", "", "Output directory for <interface>.varlink files, instead of stdout")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	path := flag.Arg(0)
+
+	descriptions, names, err := generateDescriptions(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error converting '%s': %s\n", path, err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		for i, name := range names {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(descriptions[name])
+		}
+		return
+	}
+
+	for _, name := range names {
+		filename := filepath.Join(output, name+".varlink")
+		err := ioutil.WriteFile(filename, []byte(descriptions[name]), 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file '%s': %s\n", filename, err)
+			os.Exit(1)
 		}
 	}
 }
@@ -0,0 +1,55 @@
+package drives
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// State is the state of a drive.
+type State string
+
+const (
+	StateIdle State = "idle"
+	StateBusy State = "busy"
+)
+
+// Drive is a storage device.
+type Drive struct {
+	// Name of the device node
+	Name     string            `json:"name"`
+	State    State             `json:"state"`
+	Size     uint64            `json:"size"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Engines  []Engine          `json:"engines"`
+	Attached *time.Time        `json:"attached"`
+	Extra    json.RawMessage   `json:"extra,omitempty"`
+	Ignored  string            `json:"-"`
+	internal int
+}
+
+type Engine struct {
+	ID    int64               `json:"id"`
+	Speed float64             `json:"speed"`
+	Flags map[string]struct{} `json:"flags"`
+}
+
+// NotFound is returned for unknown drives.
+//
+//varlink:error
+type NotFound struct {
+	Name string `json:"name"`
+}
+
+func (e *NotFound) Error() string { return "not found" }
+
+// Drives manages the drives of a machine.
+//
+//varlink:interface org.example.drives
+type Drives interface {
+	// List returns all drives.
+	List(ctx context.Context) (drives []Drive, err error)
+	// Get returns a drive.
+	Get(ctx context.Context, name string) (drive Drive, err error)
+	Eject(ctx context.Context, name string, force bool) error
+}
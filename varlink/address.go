@@ -0,0 +1,246 @@
+package varlink
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Address is a varlink address, like unix:/run/org.example.service;mode=0666.
+// It consists of the scheme, which selects the transport, the scheme specific
+// address, like a path or host:port, and optional parameters after ';'.
+type Address struct {
+	Scheme     string
+	Addr       string
+	Parameters map[string]string
+}
+
+// ParseAddress parses a varlink address. Parameters are separated by ';', a
+// parameter without '=' has an empty value.
+func ParseAddress(address string) (*Address, error) {
+	i := strings.IndexByte(address, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("missing protocol in address '%s'", address)
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("empty protocol in address '%s'", address)
+	}
+
+	a := Address{Scheme: address[:i]}
+	words := strings.Split(address[i+1:], ";")
+	a.Addr = words[0]
+	if a.Addr == "" {
+		return nil, fmt.Errorf("missing %s address in '%s'", a.Scheme, address)
+	}
+
+	for _, word := range words[1:] {
+		if word == "" {
+			continue
+		}
+		if a.Parameters == nil {
+			a.Parameters = make(map[string]string)
+		}
+		key, value, _ := strings.Cut(word, "=")
+		a.Parameters[key] = value
+	}
+
+	return &a, nil
+}
+
+// String returns the address in the form parsed by ParseAddress, with the
+// parameters sorted by key.
+func (a *Address) String() string {
+	var b strings.Builder
+	b.WriteString(a.Scheme + ":" + a.Addr)
+
+	keys := make([]string, 0, len(a.Parameters))
+	for key := range a.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		b.WriteString(";" + key)
+		if value := a.Parameters[key]; value != "" {
+			b.WriteString("=" + value)
+		}
+	}
+
+	return b.String()
+}
+
+// Transport connects to and listens on the addresses of a scheme.
+type Transport interface {
+	// Dial connects to the address. The dialer is the one passed to
+	// NewConnectionWithDialer, or a net.Dialer; transports not based on
+	// network connections can ignore it.
+	Dial(ctx context.Context, dialer ContextDialer, addr *Address) (net.Conn, error)
+
+	// Listen returns a listener for a Service bound to the address.
+	Listen(ctx context.Context, addr *Address) (net.Listener, error)
+}
+
+// transports maps the schemes of addresses to their transports.
+var transports = struct {
+	sync.RWMutex
+	transports map[string]Transport
+}{transports: make(map[string]Transport)}
+
+// RegisterTransport registers the transport for addresses of the scheme,
+// which are then understood by NewConnection and Service.Listen. It panics
+// if the scheme is already registered, which includes the built-in schemes
+// unix, tcp, tls, exec, vsock, ws and wss.
+func RegisterTransport(scheme string, t Transport) {
+	transports.Lock()
+	defer transports.Unlock()
+	if _, ok := transports.transports[scheme]; ok {
+		panic(fmt.Sprintf("varlink: transport for %s: addresses registered twice", scheme))
+	}
+	transports.transports[scheme] = t
+}
+
+func lookupTransport(scheme string) (Transport, error) {
+	transports.RLock()
+	defer transports.RUnlock()
+	t, ok := transports.transports[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol '%s'", scheme)
+	}
+	return t, nil
+}
+
+// builtinTransport is a transport of this package, which also handles the
+// TLS configuration of the connection, if it uses TLS.
+type builtinTransport struct {
+	dial   func(ctx context.Context, dialer ContextDialer, config *tls.Config, addr *Address) (net.Conn, error)
+	listen func(ctx context.Context, addr *Address) (net.Listener, error)
+	tls    bool
+}
+
+func (t *builtinTransport) Dial(ctx context.Context, dialer ContextDialer, addr *Address) (net.Conn, error) {
+	return t.dial(ctx, dialer, nil, addr)
+}
+
+func (t *builtinTransport) Listen(ctx context.Context, addr *Address) (net.Listener, error) {
+	if t.listen == nil {
+		return nil, fmt.Errorf("cannot listen on %s: addresses", addr.Scheme)
+	}
+	return t.listen(ctx, addr)
+}
+
+func init() {
+	RegisterTransport("unix", &builtinTransport{dial: dialNetwork, listen: listenUnix})
+	RegisterTransport("tcp", &builtinTransport{dial: dialNetwork, listen: listenTCP})
+	RegisterTransport("tls", &builtinTransport{dial: dialTLS, listen: listenTCP, tls: true})
+	RegisterTransport("exec", &builtinTransport{
+		dial: func(ctx context.Context, dialer ContextDialer, config *tls.Config, addr *Address) (net.Conn, error) {
+			return dialExec(ctx, addr.Addr)
+		},
+	})
+	RegisterTransport("vsock", &builtinTransport{
+		dial: func(ctx context.Context, dialer ContextDialer, config *tls.Config, addr *Address) (net.Conn, error) {
+			vsockAddr, err := parseVsockAddress(addr.Addr, false)
+			if err != nil {
+				return nil, err
+			}
+			return dialVsock(ctx, vsockAddr)
+		},
+		listen: func(ctx context.Context, addr *Address) (net.Listener, error) {
+			vsockAddr, err := parseVsockAddress(addr.Addr, true)
+			if err != nil {
+				return nil, err
+			}
+			return listenVsock(vsockAddr)
+		},
+	})
+	RegisterTransport("ws", &builtinTransport{dial: dialWebSocket})
+	RegisterTransport("wss", &builtinTransport{dial: dialWebSocket, tls: true})
+}
+
+// dialNetwork connects to unix: and tcp: addresses.
+func dialNetwork(ctx context.Context, dialer ContextDialer, config *tls.Config, addr *Address) (net.Conn, error) {
+	return dialer.DialContext(ctx, addr.Scheme, addr.Addr)
+}
+
+// dialTLS connects to tls: addresses.
+func dialTLS(ctx context.Context, dialer ContextDialer, config *tls.Config, addr *Address) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", addr.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return clientTLS(ctx, conn, addr.Addr, config)
+}
+
+// clientTLS performs the TLS handshake on conn to host:port. The server name
+// is taken from the address, if the configuration does not set one.
+func clientTLS(ctx context.Context, conn net.Conn, hostport string, config *tls.Config) (net.Conn, error) {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(hostport)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// listenTCP listens on tcp: and tls: addresses. The TLS handshake is done by
+// the Service.
+func listenTCP(ctx context.Context, addr *Address) (net.Listener, error) {
+	return listen(ctx, "tcp", addr.Addr)
+}
+
+// listenUnix listens on unix: addresses. A stale socket file is replaced,
+// and removed again when the listener is closed. The mode parameter, like
+// mode=0666, sets the permissions of the socket file.
+func listenUnix(ctx context.Context, addr *Address) (net.Listener, error) {
+	path := addr.Addr
+	abstract := path[0] == '@'
+
+	var mode os.FileMode
+	if value, ok := addr.Parameters["mode"]; ok && !abstract {
+		m, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode '%s' in address '%s'", value, addr)
+		}
+		mode = os.FileMode(m)
+	}
+
+	if !abstract {
+		os.Remove(path)
+	}
+
+	l, err := listen(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !abstract {
+		l.(*net.UnixListener).SetUnlinkOnClose(true)
+		if mode != 0 {
+			if err := os.Chmod(path, mode); err != nil {
+				l.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,150 @@
+package varlink_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/varlink/go/varlink"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		parsed  varlink.Address
+		str     string
+	}{
+		{"unix:/run/org.example.service", varlink.Address{Scheme: "unix", Addr: "/run/org.example.service"}, ""},
+		{"unix:@org.example.service", varlink.Address{Scheme: "unix", Addr: "@org.example.service"}, ""},
+		{"tcp:127.0.0.1:12345", varlink.Address{Scheme: "tcp", Addr: "127.0.0.1:12345"}, ""},
+		{"unix:/run/org.example.service;mode=0666;", varlink.Address{
+			Scheme:     "unix",
+			Addr:       "/run/org.example.service",
+			Parameters: map[string]string{"mode": "0666"},
+		}, "unix:/run/org.example.service;mode=0666"},
+		{"exec:/usr/bin/service;verbose;a=b=c", varlink.Address{
+			Scheme:     "exec",
+			Addr:       "/usr/bin/service",
+			Parameters: map[string]string{"verbose": "", "a": "b=c"},
+		}, "exec:/usr/bin/service;a=b=c;verbose"},
+	}
+
+	for _, tt := range tests {
+		a, err := varlink.ParseAddress(tt.address)
+		if err != nil {
+			t.Fatalf("ParseAddress(%s): %v", tt.address, err)
+		}
+		if !reflect.DeepEqual(*a, tt.parsed) {
+			t.Fatalf("ParseAddress(%s) returned %#v", tt.address, a)
+		}
+		str := tt.str
+		if str == "" {
+			str = tt.address
+		}
+		if a.String() != str {
+			t.Fatalf("String() returned '%s', expected '%s'", a, str)
+		}
+	}
+
+	for _, address := range []string{"/run/org.example.service", ":/run/org.example.service", "unix:", "unix:;mode=0666"} {
+		if _, err := varlink.ParseAddress(address); err == nil {
+			t.Fatalf("ParseAddress(%s) did not fail", address)
+		}
+	}
+}
+
+// memoryTransport connects all addresses to the same in-memory listener.
+type memoryTransport struct {
+	l *varlink.InMemoryListener
+}
+
+func (m *memoryTransport) Dial(ctx context.Context, dialer varlink.ContextDialer, addr *varlink.Address) (net.Conn, error) {
+	return m.l.DialContext(ctx, "", "")
+}
+
+func (m *memoryTransport) Listen(ctx context.Context, addr *varlink.Address) (net.Listener, error) {
+	return m.l, nil
+}
+
+func TestRegisterTransport(t *testing.T) {
+	varlink.RegisterTransport("test-memory", &memoryTransport{varlink.NewInMemoryListener()})
+
+	for _, scheme := range []string{"test-memory", "unix"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("RegisterTransport(%s) did not panic", scheme)
+				}
+			}()
+			varlink.RegisterTransport(scheme, &memoryTransport{})
+		}()
+	}
+
+	service, err := varlink.NewService("Varlink", "Transport Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+
+	ctx := context.Background()
+	if err := service.Bind(ctx, "unknown:foo"); err == nil {
+		t.Fatal("Bind() accepted an unknown protocol")
+	}
+	if err := service.Bind(ctx, "test-memory:foo"); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+	servererror := make(chan error)
+	go func() {
+		servererror <- service.DoListen(ctx, 0)
+	}()
+
+	c, err := varlink.NewConnection(ctx, "test-memory:bar")
+	if err != nil {
+		t.Fatalf("NewConnection(): %v", err)
+	}
+	var product string
+	if err := c.GetInfo(ctx, nil, &product, nil, nil, nil); err != nil {
+		t.Fatalf("GetInfo(): %v", err)
+	}
+	if product != "Transport Test" {
+		t.Fatalf("GetInfo() returned product '%s'", product)
+	}
+	c.Close()
+
+	if _, err := varlink.NewConnection(ctx, "unknown:foo"); err == nil {
+		t.Fatal("NewConnection() accepted an unknown protocol")
+	}
+
+	service.Shutdown()
+	if err := <-servererror; err != nil {
+		t.Fatalf("DoListen(): %v", err)
+	}
+}
+
+func TestUnixMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not supported")
+	}
+
+	service, err := varlink.NewService("Varlink", "Mode Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "socket")
+	if err := service.Bind(context.Background(), "unix:"+path+";mode=0600"); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+	defer service.Shutdown()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("Socket has mode %v", fi.Mode())
+	}
+}
@@ -1,19 +1,45 @@
 package varlink
 
 import (
+	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/varlink/go/varlink/internal/ctxio"
 )
 
+// bridgeTimeout is the time a bridge process has to exit after the
+// connection is closed, before it is killed.
+const bridgeTimeout = time.Second
+
+// stderrSize is the number of bytes of the standard error output of a bridge
+// process or an exec: service, which are reported when it fails.
+const stderrSize = 4096
+
 var _ net.Conn = &PipeCon{}
 
+// PipeCon is a connection to the standard input and output of a bridge
+// process. The pipes support deadlines where the platform allows, so a
+// cancelled context interrupts pending I/O.
 type PipeCon struct {
 	cmd    *exec.Cmd
-	reader io.ReadCloser
-	writer io.WriteCloser
+	reader *os.File
+	writer *os.File
+	stderr *tailBuffer
+	addr   pipeAddr
+	closed *closeResult
+}
+
+// closeResult keeps the result of closing a connection, which is returned by
+// every call of Close.
+type closeResult struct {
+	once sync.Once
+	err  error
 }
 
 func (p PipeCon) Read(b []byte) (n int, err error) {
@@ -25,37 +51,147 @@ func (p PipeCon) Write(b []byte) (n int, err error) {
 }
 
 func (p PipeCon) LocalAddr() net.Addr {
-	panic("implement me")
+	return p.addr
 }
 
 func (p PipeCon) RemoteAddr() net.Addr {
-	panic("implement me")
+	return p.addr
 }
 
 func (p PipeCon) SetDeadline(t time.Time) error {
-	panic("implement me")
+	if err := p.reader.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return p.writer.SetWriteDeadline(t)
 }
 
 func (p PipeCon) SetReadDeadline(t time.Time) error {
-	return nil
+	return p.reader.SetReadDeadline(t)
 }
 
 func (p PipeCon) SetWriteDeadline(t time.Time) error {
-	return nil
+	return p.writer.SetWriteDeadline(t)
 }
 
+// Close closes the pipes and waits for the bridge process to exit. The
+// process is killed, if it does not exit within bridgeTimeout. If the process
+// fails, the returned error carries its exit status and the end of its
+// standard error output. Further calls return the same result.
 func (p PipeCon) Close() error {
-	err1 := (p.reader).Close()
-	err2 := (p.writer).Close()
+	p.closed.once.Do(func() {
+		p.closed.err = p.close()
+	})
+	return p.closed.err
+}
+
+func (p PipeCon) close() error {
+	err1 := p.writer.Close()
+	err2 := p.reader.Close()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- p.cmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-exited:
+	case <-time.After(bridgeTimeout):
+		p.cmd.Process.Kill()
+		<-exited
+		err = fmt.Errorf("did not exit within %v and was killed", bridgeTimeout)
+	}
+
+	if err != nil {
+		if stderr := strings.TrimSpace(p.stderr.String()); stderr != "" {
+			return fmt.Errorf("bridge %s: %w: %s", p.addr, err, stderr)
+		}
+		return fmt.Errorf("bridge %s: %w", p.addr, err)
+	}
 	if err1 != nil {
 		return err1
 	}
-	if err2 != nil {
-		return err2
+	return err2
+}
+
+// pipeAddr is the address of both ends of a connection over pipes.
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+// tailBuffer keeps the last stderrSize bytes written to it.
+type tailBuffer struct {
+	mutex sync.Mutex
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > stderrSize {
+		b.buf = b.buf[len(b.buf)-stderrSize:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return string(b.buf)
+}
+
+// newBridge starts the bridge process cmd, connected by pipes, and returns a
+// connection to it.
+func newBridge(cmd *exec.Cmd, bridge string, stderr io.Writer) (*Connection, error) {
+	stdin, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer stdin.Close()
+
+	reader, stdout, err := os.Pipe()
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	defer stdout.Close()
+
+	p := &PipeCon{
+		cmd:    cmd,
+		reader: reader,
+		writer: writer,
+		stderr: &tailBuffer{},
+		addr:   pipeAddr(bridge),
+		closed: &closeResult{},
+	}
+
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = p.stderr
+	if stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderr, p.stderr)
+	}
+	// Processes started by the bridge may keep the standard error output
+	// open, do not wait for them
+	cmd.WaitDelay = bridgeTimeout
+
+	err = cmd.Start()
+	if err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, err
+	}
+
+	c := Connection{
+		address: "",
+		conn:    ctxio.NewConn(p),
 	}
-	p.cmd.Wait()
 
-	return nil
+	return &c, nil
 }
 
 // NewBridge returns a new connection with the given bridge.
@@ -0,0 +1,109 @@
+//go:build !windows
+
+package varlink_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varlink/go/varlink"
+)
+
+func TestBridgeServeStdio(t *testing.T) {
+	t.Setenv("VARLINK_TEST_STDIO_SERVICE", "1")
+	// Exit without delay in race-enabled builds, within the grace period of
+	// Close
+	t.Setenv("GORACE", "atexit_sleep_ms=0")
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c, err := varlink.NewBridge("exec '" + executable + "'")
+	if err != nil {
+		t.Fatalf("NewBridge(): %v", err)
+	}
+
+	var product string
+	for i := 0; i < 2; i++ {
+		if err := c.GetInfo(ctx, nil, &product, nil, nil, nil); err != nil {
+			t.Fatalf("GetInfo(): %v", err)
+		}
+		if product != "Exec Test" {
+			t.Fatalf("GetInfo() returned product '%s'", product)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+}
+
+func TestBridgeClose(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Cancel", func(t *testing.T) {
+		// The bridge never replies
+		c, err := varlink.NewBridgeWithStderr("cat >/dev/null", io.Discard)
+		if err != nil {
+			t.Fatalf("NewBridge(): %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		if err := c.GetInfo(ctx, nil, nil, nil, nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("GetInfo() returned %v", err)
+		}
+
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close(): %v", err)
+		}
+	})
+
+	t.Run("ExitStatus", func(t *testing.T) {
+		c, err := varlink.NewBridgeWithStderr("echo no route to host >&2; exit 3", io.Discard)
+		if err != nil {
+			t.Fatalf("NewBridge(): %v", err)
+		}
+
+		if err := c.GetInfo(ctx, nil, nil, nil, nil, nil); err == nil {
+			t.Fatal("GetInfo() did not fail")
+		}
+
+		err = c.Close()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("Close() returned %v", err)
+		}
+		if !strings.Contains(err.Error(), "no route to host") {
+			t.Fatalf("Close() did not report the standard error output: %v", err)
+		}
+		if again := c.Close(); again != err {
+			t.Fatalf("Second Close() returned %v", again)
+		}
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		// The bridge ignores the end of its input
+		c, err := varlink.NewBridgeWithStderr("exec sleep 10", io.Discard)
+		if err != nil {
+			t.Fatalf("NewBridge(): %v", err)
+		}
+
+		start := time.Now()
+		if err := c.Close(); err == nil {
+			t.Fatal("Close() did not report the killed bridge")
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Fatalf("Close() took %v", d)
+		}
+	})
+}
@@ -2,7 +2,10 @@ package varlink
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -17,8 +20,26 @@ type Call struct {
 	In        *serviceCall
 	Continues bool
 	Upgrade   bool
+
+	state *callState
+}
+
+// callState tracks the replies sent to a method call, it is shared by all
+// copies of the Call.
+type callState struct {
+	continued bool  // a reply with continues was sent
+	finished  bool  // the final reply was sent
+	err       error // a reply could not be sent, the call can not continue
 }
 
+// ErrMoreNotRequested is returned when sending a reply with continues to a
+// method call, which did not set more.
+var ErrMoreNotRequested = errors.New("call did not set more, it does not expect continues")
+
+// ErrCallFinished is returned when sending a reply to a method call, which
+// already received its final reply.
+var ErrCallFinished = errors.New("call already received its final reply")
+
 // WantsMore indicates if the calling client accepts more than one reply to this method call.
 func (c *Call) WantsMore() bool {
 	return c.In.More
@@ -34,6 +55,38 @@ func (c *Call) IsOneway() bool {
 	return c.In.Oneway
 }
 
+// PeerCertificate returns the verified certificate of the calling client on
+// a tls: connection, or nil if the client did not present one.
+func (c *Call) PeerCertificate() *x509.Certificate {
+	nc, ok := c.Conn.(GetNetConn)
+	if !ok {
+		return nil
+	}
+	tlsConn, ok := nc.NetConn().(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// PeerCID returns the context identifier (CID) of the calling client on a
+// vsock: connection, which identifies its virtual machine.
+func (c *Call) PeerCID() (uint32, bool) {
+	nc, ok := c.Conn.(GetNetConn)
+	if !ok {
+		return 0, false
+	}
+	addr, ok := nc.NetConn().RemoteAddr().(*VsockAddr)
+	if !ok {
+		return 0, false
+	}
+	return addr.CID, true
+}
+
 // GetParameters retrieves the method call parameters.
 func (c *Call) GetParameters(p interface{}) error {
 	if c.In.Parameters == nil {
@@ -43,7 +96,17 @@ func (c *Call) GetParameters(p interface{}) error {
 }
 
 func (c *Call) sendMessage(ctx context.Context, r *serviceReply) error {
+	if c.state != nil {
+		if c.state.err != nil {
+			return c.state.err
+		}
+		if c.state.finished {
+			return ErrCallFinished
+		}
+	}
+
 	if c.In.Oneway {
+		c.sent(r, nil)
 		return nil
 	}
 
@@ -56,11 +119,50 @@ func (c *Call) sendMessage(ctx context.Context, r *serviceReply) error {
 
 	_, err = c.Conn.Write(ctx, b)
 	if err == io.EOF {
-		return io.ErrUnexpectedEOF
+		err = io.ErrUnexpectedEOF
 	}
+	c.sent(r, err)
 	return err
 }
 
+// sent records a reply, or the error which prevented sending it.
+func (c *Call) sent(r *serviceReply, err error) {
+	if c.state == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		c.state.err = err
+	case r.Continues:
+		c.state.continued = true
+	default:
+		c.state.finished = true
+	}
+}
+
+// Stream sends a reply to this method call, which is followed by more
+// replies. It fails with ErrMoreNotRequested if the client did not set more.
+// If a reply can not be sent, because the client disconnected, Stream and
+// all further replies return the error; the method should stop streaming and
+// return it.
+func (c *Call) Stream(ctx context.Context, parameters interface{}) error {
+	if !c.In.More {
+		return ErrMoreNotRequested
+	}
+	return c.sendMessage(ctx, &serviceReply{
+		Continues:  true,
+		Parameters: parameters,
+	})
+}
+
+// Finish sends the final reply to this method call, which ends a stream of
+// replies sent with Stream.
+func (c *Call) Finish(ctx context.Context, parameters interface{}) error {
+	return c.sendMessage(ctx, &serviceReply{
+		Parameters: parameters,
+	})
+}
+
 // Reply sends a reply to this method call.
 func (c *Call) Reply(ctx context.Context, parameters interface{}) error {
 	if !c.Continues {
@@ -70,7 +172,7 @@ func (c *Call) Reply(ctx context.Context, parameters interface{}) error {
 	}
 
 	if !c.In.More {
-		return fmt.Errorf("call did not set more, it does not expect continues")
+		return ErrMoreNotRequested
 	}
 
 	return c.sendMessage(ctx, &serviceReply{
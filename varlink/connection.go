@@ -2,11 +2,11 @@ package varlink
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
-	"strings"
 
 	"github.com/varlink/go/varlink/internal/ctxio"
 )
@@ -21,56 +21,14 @@ const (
 	Upgrade   = 1 << iota
 )
 
-// Error is a varlink error returned from a method call.
+// Error is a varlink error returned from a method call. Received errors,
+// which are not registered with RegisterError, carry their parameters as
+// *json.RawMessage; GetParameters decodes them.
 type Error struct {
 	Name       string
 	Parameters interface{}
 }
 
-func (e *Error) DispatchError() error {
-	errorRawParameters := e.Parameters.(*json.RawMessage)
-
-	switch e.Name {
-	case "org.varlink.service.InterfaceNotFound":
-		var param InterfaceNotFound
-		if errorRawParameters != nil {
-			err := json.Unmarshal(*errorRawParameters, &param)
-			if err != nil {
-				return e
-			}
-		}
-		return &param
-	case "org.varlink.service.MethodNotFound":
-		var param MethodNotFound
-		if errorRawParameters != nil {
-			err := json.Unmarshal(*errorRawParameters, &param)
-			if err != nil {
-				return e
-			}
-		}
-		return &param
-	case "org.varlink.service.MethodNotImplemented":
-		var param MethodNotImplemented
-		if errorRawParameters != nil {
-			err := json.Unmarshal(*errorRawParameters, &param)
-			if err != nil {
-				return e
-			}
-		}
-		return &param
-	case "org.varlink.service.InvalidParameter":
-		var param InvalidParameter
-		if errorRawParameters != nil {
-			err := json.Unmarshal(*errorRawParameters, &param)
-			if err != nil {
-				return e
-			}
-		}
-		return &param
-	}
-	return e
-}
-
 // Error returns the fully-qualified varlink error name.
 func (e *Error) Error() string {
 	return e.Name
@@ -108,6 +66,8 @@ type Connection struct {
 // Send sends a method call. It returns a receive() function which is called to retrieve the method reply.
 // If Send() is called with the `More` flag and the receive() function carries the `Continues` flag, receive()
 // can be called multiple times to retrieve multiple replies.
+// If Send() is called with the `Oneway` flag, the service does not reply and receive() returns immediately
+// without reading from the connection.
 func (c *Connection) Send(ctx context.Context, method string, parameters interface{}, flags uint64) (func(context.Context, interface{}) (uint64, error), error) {
 	type call struct {
 		Method     string      `json:"method"`
@@ -153,6 +113,12 @@ func (c *Connection) Send(ctx context.Context, method string, parameters interfa
 		return nil, err
 	}
 
+	if m.Oneway {
+		return func(context.Context, interface{}) (uint64, error) {
+			return 0, nil
+		}, nil
+	}
+
 	receive := func(ctx context.Context, outParameters interface{}) (uint64, error) {
 		type reply struct {
 			Parameters *json.RawMessage `json:"parameters"`
@@ -286,8 +252,25 @@ func (c *Connection) Close() error {
 // NewConnection returns a new connection to the given address. The context
 // is used when dialling. Once successfully connected, any expiration
 // of the context will not affect the connection.
+//
+// An exec: address, like exec:/usr/libexec/org.example.service, starts the
+// executable with a listening socket passed by socket activation
+// (LISTEN_FDS), and connects to it. The executable is started by /bin/sh,
+// which sets LISTEN_PID to its own pid before replacing itself with the
+// service, so exec: addresses require a POSIX shell. Close terminates the
+// service, and reports its exit status and the end of its standard error
+// output, if it failed.
+//
+// A vsock: address, like vsock:3:1234, connects to the port of the virtual
+// machine with the context identifier (CID), see VsockAddr.
+//
+// A ws: or wss: address, like wss:example.com:8443/varlink, connects to a
+// WebSocket endpoint, like one served by Service.WebSocketHandler.
+//
+// Other schemes are handled by the transports registered with
+// RegisterTransport.
 func NewConnection(ctx context.Context, address string) (*Connection, error) {
-	return newConnectionWithDialer(ctx, address, &net.Dialer{})
+	return newConnectionWithDialer(ctx, address, &net.Dialer{}, nil)
 }
 
 // NewConnectionWithDialer returns a new connection to the given address using a custom dialer.
@@ -311,39 +294,50 @@ func NewConnectionWithDialer(ctx context.Context, address string, dialer Context
 	if dialer == nil {
 		return nil, fmt.Errorf("dialer cannot be nil")
 	}
-	return newConnectionWithDialer(ctx, address, dialer)
+	return newConnectionWithDialer(ctx, address, dialer, nil)
 }
 
-// newConnectionWithDialer is the private implementation used by both NewConnection
-// and NewConnectionWithDialer.
-func newConnectionWithDialer(ctx context.Context, address string, dialer ContextDialer) (*Connection, error) {
-	words := strings.SplitN(address, ":", 2)
-
-	if len(words) != 2 {
-		return nil, fmt.Errorf("Protocol missing")
+// NewConnectionWithTLS returns a new connection to the given tls: or wss:
+// address, like tls:example.com:1234, using the TLS configuration. The server name is
+// taken from the address, if the configuration does not set one. To
+// authenticate with a client certificate, set Certificates.
+//
+// Addresses of other schemes, including those of transports registered with
+// RegisterTransport, do not use TLS and are rejected.
+//
+// NewConnection connects to tls: addresses with the default configuration,
+// which verifies the server certificate with the system roots.
+func NewConnectionWithTLS(ctx context.Context, address string, config *tls.Config) (*Connection, error) {
+	if config == nil {
+		return nil, fmt.Errorf("TLS configuration cannot be nil")
 	}
+	return newConnectionWithDialer(ctx, address, &net.Dialer{}, config)
+}
 
-	protocol := words[0]
-	addr := words[1]
-
-	// Ignore parameters after ';'
-	words = strings.SplitN(addr, ";", 2)
-	if words != nil {
-		addr = words[0]
+// newConnectionWithDialer is the private implementation used by NewConnection,
+// NewConnectionWithDialer and NewConnectionWithTLS.
+func newConnectionWithDialer(ctx context.Context, address string, dialer ContextDialer, config *tls.Config) (*Connection, error) {
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
 	}
 
-	switch protocol {
-	case "unix":
-		break
-
-	case "tcp":
-		break
+	t, err := lookupTransport(addr.Scheme)
+	if err != nil {
+		return nil, err
+	}
 
-	default:
-		return nil, fmt.Errorf("unknown protocol %s", protocol)
+	b, builtin := t.(*builtinTransport)
+	if config != nil && (!builtin || !b.tls) {
+		return nil, fmt.Errorf("%s: addresses do not use TLS", addr.Scheme)
 	}
 
-	conn, err := dialer.DialContext(ctx, protocol, addr)
+	var conn net.Conn
+	if builtin {
+		conn, err = b.dial(ctx, dialer, config, addr)
+	} else {
+		conn, err = t.Dial(ctx, dialer, addr)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,13 @@
 package varlink
 
 import (
+	"bufio"
 	"context"
 	"net"
 	"testing"
+	"time"
+
+	"github.com/varlink/go/varlink/internal/ctxio"
 )
 
 func TestNewConnection(t *testing.T) {
@@ -64,3 +68,48 @@ func (d *testDialer) DialContext(ctx context.Context, network, address string) (
 
 	return d.Dialer.DialContext(ctx, network, address)
 }
+
+func TestOneway(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	c := &Connection{conn: ctxio.NewConn(client)}
+
+	// The server reads both calls, and only replies to the second one
+	calls := make(chan string, 2)
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+		for i := 0; i < 2; i++ {
+			call, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			calls <- call
+		}
+		server.Write([]byte(`{"parameters":{"n":1}}` + "\000"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receive, err := c.Send(ctx, "org.example.test.Notify", nil, Oneway)
+	if err != nil {
+		t.Fatalf("Send(): %v", err)
+	}
+	flags, err := receive(ctx, nil)
+	if err != nil || flags != 0 {
+		t.Fatalf("receive() returned %d, %v", flags, err)
+	}
+	expect(t, `{"method":"org.example.test.Notify","oneway":true}`+"\000", <-calls)
+
+	var out struct {
+		N int `json:"n"`
+	}
+	if err := c.Call(ctx, "org.example.test.Get", nil, &out); err != nil {
+		t.Fatalf("Call(): %v", err)
+	}
+	expect(t, `{"method":"org.example.test.Get","parameters":null}`+"\000", <-calls)
+	if out.N != 1 {
+		t.Fatalf("Call() returned %d", out.N)
+	}
+}
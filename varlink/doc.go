@@ -100,5 +100,40 @@ Custom dialer for connecting to a Unix socket on a remote host via SSH:
 
 	// Connect to Unix socket on the remote host
 	conn, err := varlink.NewConnectionWithDialer(ctx, "unix:/run/org.example.service", &sshDialer{sshClient})
+
+Service accepting TLS connections, which authenticates its clients with
+certificates:
+
+	service.SetTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{serverCertificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+	err := service.Listen(ctx, "tls:0.0.0.0:12345", 0)
+
+	// In the method implementation
+	cert := call.PeerCertificate()
+
+Client connecting to it:
+
+	conn, err := varlink.NewConnectionWithTLS(ctx, "tls:example.org:12345", &tls.Config{
+		Certificates: []tls.Certificate{clientCertificate},
+		RootCAs:      serverCAs,
+	})
+
+Service reachable by WebSocket, e.g. from browsers, where every varlink message
+is carried in a text message:
+
+	http.Handle("/varlink", service.WebSocketHandler())
+	err := http.ListenAndServe(":8080", nil)
+
+Browsers may only connect from pages of the same host, or of the origins
+passed to WebSocketHandler:
+
+	http.Handle("/varlink", service.WebSocketHandler("https://example.org"))
+
+Client connecting to it:
+
+	conn, err := varlink.NewConnection(ctx, "ws:example.org:8080/varlink")
 */
 package varlink
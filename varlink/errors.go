@@ -0,0 +1,152 @@
+package varlink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// errorTypes maps the names of registered varlink errors to their Go types,
+// and back.
+var errorTypes = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}{
+	types: make(map[string]reflect.Type),
+	names: make(map[reflect.Type]string),
+}
+
+// RegisterError registers the Go type of a varlink error. Errors with this
+// name received by a Connection are returned as a pointer to a new value of
+// the type, with the error parameters decoded into it. The error passed is
+// only used for its type, which must be a struct or a pointer to a struct.
+// Generated interface packages register their errors when they are
+// initialized. RegisterError panics if the name is already registered with a
+// different type.
+func RegisterError(name string, err error) {
+	t := reflect.TypeOf(err)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("varlink: error type %T of %s is not a struct", err, name))
+	}
+	if _, ok := reflect.New(t).Interface().(error); !ok {
+		panic(fmt.Sprintf("varlink: error type %T of %s does not implement error", err, name))
+	}
+
+	errorTypes.Lock()
+	defer errorTypes.Unlock()
+	if registered, ok := errorTypes.types[name]; ok && registered != t {
+		panic(fmt.Sprintf("varlink: error %s registered twice, as %v and %v", name, registered, t))
+	}
+	errorTypes.types[name] = t
+	errorTypes.names[t] = name
+}
+
+func lookupError(name string) reflect.Type {
+	errorTypes.RLock()
+	defer errorTypes.RUnlock()
+	return errorTypes.types[name]
+}
+
+func lookupErrorName(t reflect.Type) (string, bool) {
+	errorTypes.RLock()
+	defer errorTypes.RUnlock()
+	name, ok := errorTypes.names[t]
+	return name, ok
+}
+
+func init() {
+	RegisterError("org.varlink.service.InterfaceNotFound", &InterfaceNotFound{})
+	RegisterError("org.varlink.service.MethodNotFound", &MethodNotFound{})
+	RegisterError("org.varlink.service.MethodNotImplemented", &MethodNotImplemented{})
+	RegisterError("org.varlink.service.InvalidParameter", &InvalidParameter{})
+}
+
+// rawParameters returns the parameters of the error as JSON, or nil if it
+// has none.
+func (e *Error) rawParameters() (json.RawMessage, error) {
+	switch p := e.Parameters.(type) {
+	case nil:
+		return nil, nil
+	case *json.RawMessage:
+		if p == nil {
+			return nil, nil
+		}
+		return *p, nil
+	case json.RawMessage:
+		return p, nil
+	default:
+		return json.Marshal(p)
+	}
+}
+
+// GetParameters decodes the parameters of the error into p. It is used to
+// inspect errors, which are not registered with RegisterError.
+func (e *Error) GetParameters(p interface{}) error {
+	raw, err := e.rawParameters()
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return fmt.Errorf("empty parameters")
+	}
+	return json.Unmarshal(raw, p)
+}
+
+// Is reports whether target is a varlink Error with the same name, for use
+// with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Name == e.Name
+}
+
+// DispatchError returns the error as the Go type registered for its name
+// with RegisterError. It returns the Error itself, if the name is not
+// registered or the parameters do not match the type.
+func (e *Error) DispatchError() error {
+	t := lookupError(e.Name)
+	if t == nil {
+		return e
+	}
+
+	raw, err := e.rawParameters()
+	if err != nil {
+		return e
+	}
+
+	v := reflect.New(t)
+	if raw != nil && string(raw) != "null" {
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			return e
+		}
+	}
+	return v.Interface().(error)
+}
+
+// ToError returns err as a varlink Error with the name and parameters of the
+// error, to reply or forward it. It finds an *Error or a value of a type
+// registered with RegisterError in the chain of err. It returns false for
+// other errors, like I/O errors of the connection.
+func ToError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		t := reflect.TypeOf(err)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if name, ok := lookupErrorName(t); ok {
+			return &Error{Name: name, Parameters: err}, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,123 @@
+package varlink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type testError struct {
+	Reason string `json:"reason"`
+}
+
+func (e testError) Error() string {
+	return "org.example.errors.TestError"
+}
+
+type otherError struct{}
+
+func (e otherError) Error() string {
+	return "org.example.errors.TestError"
+}
+
+func init() {
+	RegisterError("org.example.errors.TestError", &testError{})
+}
+
+func TestDispatchError(t *testing.T) {
+	raw := json.RawMessage(`{"reason":"broken"}`)
+
+	for _, parameters := range []interface{}{
+		&raw,
+		raw,
+		map[string]string{"reason": "broken"},
+	} {
+		err := (&Error{Name: "org.example.errors.TestError", Parameters: parameters}).DispatchError()
+		var e *testError
+		if !errors.As(err, &e) {
+			t.Fatalf("DispatchError() returned %T", err)
+		}
+		expect(t, "broken", e.Reason)
+	}
+
+	for _, parameters := range []interface{}{nil, (*json.RawMessage)(nil)} {
+		err := (&Error{Name: "org.example.errors.TestError", Parameters: parameters}).DispatchError()
+		if _, ok := err.(*testError); !ok {
+			t.Fatalf("DispatchError() returned %T", err)
+		}
+	}
+
+	invalid := json.RawMessage(`{"reason":1}`)
+	unregistered := &Error{Name: "org.example.errors.Unknown", Parameters: &raw}
+	for _, e := range []*Error{
+		{Name: "org.example.errors.TestError", Parameters: &invalid},
+		{Name: "org.example.errors.TestError", Parameters: func() {}},
+		unregistered,
+	} {
+		if err := e.DispatchError(); err != e {
+			t.Fatalf("DispatchError() returned %v", err)
+		}
+	}
+
+	var p testError
+	if err := unregistered.GetParameters(&p); err != nil {
+		t.Fatalf("GetParameters(): %v", err)
+	}
+	expect(t, "broken", p.Reason)
+	if err := (&Error{Name: "org.example.errors.Unknown"}).GetParameters(&p); err == nil {
+		t.Fatal("GetParameters() without parameters did not fail")
+	}
+
+	if !errors.Is(unregistered, &Error{Name: "org.example.errors.Unknown"}) {
+		t.Fatal("errors.Is() does not match the error name")
+	}
+	if errors.Is(unregistered, &Error{Name: "org.example.errors.Other"}) {
+		t.Fatal("errors.Is() matches a different error name")
+	}
+
+	err := (&Error{Name: "org.varlink.service.MethodNotFound", Parameters: &raw}).DispatchError()
+	if !errors.Is(err, &MethodNotFound{}) || errors.Is(err, &MethodNotImplemented{}) {
+		t.Fatalf("errors.Is() does not match %T", err)
+	}
+}
+
+func TestRegisterError(t *testing.T) {
+	// Registering the same type again is allowed
+	RegisterError("org.example.errors.TestError", testError{})
+
+	// A different type, or one which is not a struct, is refused
+	for _, err := range []error{otherError{}, nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("RegisterError(%T) did not panic", err)
+				}
+			}()
+			RegisterError("org.example.errors.TestError", err)
+		}()
+	}
+}
+
+func TestToError(t *testing.T) {
+	e, ok := ToError(fmt.Errorf("wrapped: %w", &testError{Reason: "broken"}))
+	if !ok {
+		t.Fatal("ToError() did not find the registered error")
+	}
+	expect(t, "org.example.errors.TestError", e.Name)
+	raw, err := e.rawParameters()
+	if err != nil {
+		t.Fatalf("rawParameters(): %v", err)
+	}
+	expect(t, `{"reason":"broken"}`, string(raw))
+
+	unregistered := &Error{Name: "org.example.errors.Unknown"}
+	if e, ok := ToError(unregistered); !ok || e != unregistered {
+		t.Fatalf("ToError() returned %v", e)
+	}
+
+	if _, ok := ToError(io.ErrUnexpectedEOF); ok {
+		t.Fatal("ToError() accepted an I/O error")
+	}
+}
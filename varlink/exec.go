@@ -0,0 +1,135 @@
+//go:build !windows
+
+package varlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// execTimeout is the time a service started for an exec: address has to exit
+// after the connection is closed, before it is killed.
+const execTimeout = time.Second
+
+// execHelper is the shell command starting the service for an exec: address.
+// LISTEN_PID must be the pid of the service, which is only known in the new
+// process; the shell sets it to its own pid and replaces itself with the
+// service, which is passed as $0.
+const execHelper = `LISTEN_PID=$$ exec "$0"`
+
+// execConn is a connection to a service started for an exec: address.
+type execConn struct {
+	*net.UnixConn
+	cmd    *exec.Cmd
+	path   string
+	stderr *tailBuffer
+	closed closeResult
+}
+
+// Close closes the connection, terminates the service and waits for it to
+// exit. If the service failed, other than by being terminated by Close, the
+// returned error carries its exit status and the end of its standard error
+// output. Further calls return the same result.
+func (c *execConn) Close() error {
+	c.closed.once.Do(func() {
+		c.closed.err = c.close()
+	})
+	return c.closed.err
+}
+
+func (c *execConn) close() error {
+	err := c.UnixConn.Close()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- c.cmd.Wait()
+	}()
+
+	c.cmd.Process.Signal(syscall.SIGTERM)
+	var waitErr error
+	select {
+	case waitErr = <-exited:
+	case <-time.After(execTimeout):
+		c.cmd.Process.Kill()
+		waitErr = <-exited
+	}
+
+	if waitErr != nil && !terminated(waitErr) {
+		if stderr := strings.TrimSpace(c.stderr.String()); stderr != "" {
+			return fmt.Errorf("exec %s: %w: %s", c.path, waitErr, stderr)
+		}
+		return fmt.Errorf("exec %s: %w", c.path, waitErr)
+	}
+	return err
+}
+
+// terminated reports whether the error of Wait is caused by the signals sent
+// by Close.
+func terminated(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && (status.Signal() == syscall.SIGTERM || status.Signal() == syscall.SIGKILL)
+}
+
+// dialExec starts the executable at path with a listening socket passed by
+// socket activation, and returns a connection to it. The service is started
+// by /bin/sh, see execHelper.
+func dialExec(ctx context.Context, path string) (net.Conn, error) {
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "varlink-exec-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	file, err := listener.File()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stderr := &tailBuffer{}
+	cmd := exec.Command("/bin/sh", "-c", execHelper, path)
+	cmd.Env = append(os.Environ(), "LISTEN_FDS=1", "LISTEN_FDNAMES=varlink")
+	cmd.ExtraFiles = []*os.File{file}
+	cmd.Stderr = stderr
+	// Processes started by the service may keep the standard error output
+	// open, do not wait for them
+	cmd.WaitDelay = execTimeout
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	return &execConn{UnixConn: conn.(*net.UnixConn), cmd: cmd, path: path, stderr: stderr}, nil
+}
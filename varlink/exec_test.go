@@ -0,0 +1,94 @@
+//go:build !windows
+
+package varlink_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/varlink/go/varlink"
+)
+
+// TestMain runs the test binary as a service for exec: addresses, if it is
+// started with VARLINK_TEST_EXEC_SERVICE, or as a bridge service over stdio,
+// if it is started with VARLINK_TEST_STDIO_SERVICE. A service started with
+// VARLINK_TEST_EXEC_SERVICE=fail exits with an error.
+func TestMain(m *testing.M) {
+	stdio := os.Getenv("VARLINK_TEST_STDIO_SERVICE") != ""
+	if os.Getenv("VARLINK_TEST_EXEC_SERVICE") == "" && !stdio {
+		os.Exit(m.Run())
+	}
+	if os.Getenv("VARLINK_TEST_EXEC_SERVICE") == "fail" {
+		fmt.Fprintln(os.Stderr, "service failed")
+		os.Exit(3)
+	}
+
+	service, err := varlink.NewService("Varlink", "Exec Test", "1", "https://github.com/varlink/go/varlink")
+	if err == nil {
+		err = service.RegisterInterface(new(VarlinkInterface))
+	}
+	if err == nil && stdio {
+		err = service.ServeStdio(context.Background())
+	} else if err == nil {
+		// The socket passed by activation replaces the address
+		err = service.Listen(context.Background(), "unix:/nonexistent", 0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestExec(t *testing.T) {
+	t.Setenv("VARLINK_TEST_EXEC_SERVICE", "1")
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c, err := varlink.NewConnection(ctx, "exec:"+executable)
+	if err != nil {
+		t.Fatalf("NewConnection(): %v", err)
+	}
+
+	var product string
+	for i := 0; i < 2; i++ {
+		if err := c.GetInfo(ctx, nil, &product, nil, nil, nil); err != nil {
+			t.Fatalf("GetInfo(): %v", err)
+		}
+		if product != "Exec Test" {
+			t.Fatalf("GetInfo() returned product '%s'", product)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	if _, err := varlink.NewConnection(ctx, "exec:/nonexistent"); err == nil {
+		t.Fatal("NewConnection() with a missing executable did not fail")
+	}
+
+	t.Setenv("VARLINK_TEST_EXEC_SERVICE", "fail")
+	c, err = varlink.NewConnection(ctx, "exec:"+executable)
+	if err != nil {
+		t.Fatalf("NewConnection(): %v", err)
+	}
+	// The call fails once the service exited, before Close terminates it
+	if err := c.GetInfo(ctx, nil, &product, nil, nil, nil); err == nil {
+		t.Fatal("GetInfo() on a failed service did not fail")
+	}
+	err = c.Close()
+	if err == nil || !strings.Contains(err.Error(), "exit status 3: service failed") {
+		t.Fatalf("Close() returned %v", err)
+	}
+	if again := c.Close(); again != err {
+		t.Fatalf("Second Close() returned %v", again)
+	}
+}
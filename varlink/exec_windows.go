@@ -0,0 +1,11 @@
+package varlink
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+func dialExec(ctx context.Context, path string) (net.Conn, error) {
+	return nil, fmt.Errorf("exec addresses are not supported on windows")
+}
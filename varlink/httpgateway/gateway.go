@@ -0,0 +1,350 @@
+// Package httpgateway provides an HTTP/JSON gateway to a varlink service, for
+// debugging with curl and for integrating with HTTP-only systems.
+//
+// A method call is a POST request to /<interface>/<Method>, with the call
+// parameters as JSON object in the body:
+//
+//	curl -H 'Content-Type: application/json' \
+//		-d '{"interface":"org.varlink.service"}' \
+//		http://localhost:8080/org.varlink.service/GetInterfaceDescription
+//
+// The Content-Type of the request must be application/json, also for calls
+// without parameters; other requests are answered with 415 Unsupported Media
+// Type. Browsers do not send such requests to other sites without asking
+// them by a CORS preflight request, which the gateway does not answer, so
+// pages of other sites cannot call methods in the name of a browser user.
+//
+// The reply parameters are returned as JSON object. A varlink error is
+// returned with its name and parameters, like
+// {"error":"org.varlink.service.InvalidParameter","parameters":{"parameter":"interface"}}.
+// The HTTP status code is 404 for an unknown interface or method, 501 for an
+// unimplemented method, 400 for invalid parameters and 500 for all other
+// errors.
+//
+// Requests accepting application/x-ndjson or text/event-stream call the
+// method with the More flag, and stream the replies as newline-delimited
+// JSON or as Server-Sent Events. An error ends the stream, as a JSON object
+// with the error name, or as an "error" event.
+package httpgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/varlink/go/varlink"
+)
+
+// MaxRequestSize is the maximum size of the request body.
+const MaxRequestSize = 16 << 20
+
+// backend calls a method and passes every reply to the reply function.
+type backend interface {
+	call(ctx context.Context, method string, parameters json.RawMessage, more bool, reply func(json.RawMessage) error) error
+}
+
+// Handler is an http.Handler, which calls the methods of a varlink service.
+type Handler struct {
+	backend backend
+}
+
+// NewServiceHandler returns a Handler calling the methods of the in-process
+// service.
+func NewServiceHandler(service *varlink.Service) *Handler {
+	return &Handler{backend: &serviceBackend{service: service}}
+}
+
+// NewConnectionHandler returns a Handler calling the methods on the
+// connection. Calls are serialized, since a connection handles one call at a
+// time. A call interrupted by a cancelled request may leave the connection
+// unusable, so it should not be shared with other users.
+func NewConnectionHandler(conn *varlink.Connection) *Handler {
+	return &Handler{backend: &connectionBackend{conn: conn}}
+}
+
+// serviceBackend calls the methods of an in-process service.
+type serviceBackend struct {
+	service *varlink.Service
+}
+
+// replyWriter receives the replies of a service to a single call.
+type replyWriter struct {
+	reply func(json.RawMessage) error
+	err   error
+}
+
+func (w *replyWriter) Write(ctx context.Context, b []byte) (int, error) {
+	var m struct {
+		Parameters json.RawMessage `json:"parameters"`
+		Error      string          `json:"error"`
+	}
+	if err := json.Unmarshal(bytes.TrimSuffix(b, []byte{0}), &m); err != nil {
+		return 0, err
+	}
+
+	if m.Error != "" {
+		w.err = &varlink.Error{Name: m.Error, Parameters: m.Parameters}
+		return len(b), nil
+	}
+	if err := w.reply(m.Parameters); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *replyWriter) Read(context.Context, []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (w *replyWriter) ReadBytes(context.Context, byte) ([]byte, error) {
+	return nil, io.EOF
+}
+
+func (b *serviceBackend) call(ctx context.Context, method string, parameters json.RawMessage, more bool, reply func(json.RawMessage) error) error {
+	request, err := json.Marshal(struct {
+		Method     string          `json:"method"`
+		Parameters json.RawMessage `json:"parameters,omitempty"`
+		More       bool            `json:"more,omitempty"`
+	}{method, parameters, more})
+	if err != nil {
+		return err
+	}
+
+	w := &replyWriter{reply: reply}
+	if err := b.service.HandleMessage(ctx, w, request); err != nil {
+		return err
+	}
+	return w.err
+}
+
+// connectionBackend calls the methods on a connection.
+type connectionBackend struct {
+	mutex sync.Mutex
+	conn  *varlink.Connection
+}
+
+func (b *connectionBackend) call(ctx context.Context, method string, parameters json.RawMessage, more bool, reply func(json.RawMessage) error) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var in interface{}
+	if parameters != nil {
+		in = parameters
+	}
+	var flags uint64
+	if more {
+		flags = varlink.More
+	}
+
+	receive, err := b.conn.Send(ctx, method, in, flags)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var out json.RawMessage
+		flags, err := receive(ctx, &out)
+		if err != nil {
+			return err
+		}
+		if err := reply(out); err != nil {
+			return err
+		}
+		if flags&varlink.Continues == 0 {
+			return nil
+		}
+	}
+}
+
+// statusCode returns the HTTP status code for the varlink error.
+func statusCode(name string) int {
+	switch name {
+	case "org.varlink.service.InterfaceNotFound", "org.varlink.service.MethodNotFound":
+		return http.StatusNotFound
+	case "org.varlink.service.MethodNotImplemented":
+		return http.StatusNotImplemented
+	case "org.varlink.service.InvalidParameter":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorBody returns the JSON object carrying the error name and parameters,
+// and the HTTP status code for the error.
+func errorBody(err error) ([]byte, int) {
+	type body struct {
+		Error      string      `json:"error"`
+		Parameters interface{} `json:"parameters,omitempty"`
+	}
+
+	e, ok := varlink.ToError(err)
+	if !ok {
+		b, _ := json.Marshal(body{Error: err.Error()})
+		return b, http.StatusInternalServerError
+	}
+
+	var parameters json.RawMessage
+	if e.GetParameters(&parameters) != nil || string(parameters) == "null" {
+		b, _ := json.Marshal(body{Error: e.Name})
+		return b, statusCode(e.Name)
+	}
+	b, _ := json.Marshal(body{Error: e.Name, Parameters: parameters})
+	return b, statusCode(e.Name)
+}
+
+// compact returns the parameters as JSON object on a single line.
+func compact(parameters json.RawMessage) []byte {
+	var b bytes.Buffer
+	if len(parameters) == 0 || string(parameters) == "null" || json.Compact(&b, parameters) != nil {
+		return []byte("{}")
+	}
+	return b.Bytes()
+}
+
+// stream formats the replies of a call with the More flag.
+type stream struct {
+	contentType string
+	reply       func(w io.Writer, parameters []byte)
+	error       func(w io.Writer, body []byte)
+}
+
+var streams = map[string]stream{
+	"application/x-ndjson": {
+		contentType: "application/x-ndjson",
+		reply: func(w io.Writer, parameters []byte) {
+			fmt.Fprintf(w, "%s\n", parameters)
+		},
+		error: func(w io.Writer, body []byte) {
+			fmt.Fprintf(w, "%s\n", body)
+		},
+	},
+	"text/event-stream": {
+		contentType: "text/event-stream",
+		reply: func(w io.Writer, parameters []byte) {
+			fmt.Fprintf(w, "data: %s\n\n", parameters)
+		},
+		error: func(w io.Writer, body []byte) {
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", body)
+		},
+	},
+}
+
+// acceptedStream returns the stream format accepted by the request.
+func acceptedStream(r *http.Request) (stream, bool) {
+	for _, value := range r.Header.Values("Accept") {
+		for _, t := range strings.Split(value, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(t))
+			if err != nil {
+				continue
+			}
+			if s, ok := streams[mediaType]; ok {
+				return s, true
+			}
+		}
+	}
+	return stream{}, false
+}
+
+// parseRequest returns the method name and the parameters of the request.
+func parseRequest(r *http.Request) (string, json.RawMessage, error) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	i := strings.LastIndexByte(path, '/')
+	if i <= 0 || i == len(path)-1 || strings.Contains(path[:i], "/") {
+		return "", nil, &varlink.MethodNotFound{Method: path}
+	}
+	method := path[:i] + "." + path[i+1:]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, &varlink.InvalidParameter{Parameter: "parameters"}
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return method, nil, nil
+	}
+
+	var parameters map[string]json.RawMessage
+	if err := json.Unmarshal(body, &parameters); err != nil || parameters == nil {
+		return "", nil, &varlink.InvalidParameter{Parameter: "parameters"}
+	}
+
+	return method, json.RawMessage(body), nil
+}
+
+// ServeHTTP calls the method of the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "Unsupported media type, expected application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestSize)
+	method, parameters, err := parseRequest(r)
+	if err != nil {
+		body, code := errorBody(err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		w.Write(append(body, '\n'))
+		return
+	}
+
+	s, more := acceptedStream(r)
+	if !more {
+		var out json.RawMessage
+		err := h.backend.call(r.Context(), method, parameters, false, func(parameters json.RawMessage) error {
+			out = parameters
+			return nil
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			body, code := errorBody(err)
+			w.WriteHeader(code)
+			w.Write(append(body, '\n'))
+			return
+		}
+		w.Write(append(compact(out), '\n'))
+		return
+	}
+
+	// The status is sent with the first reply, an error before it is
+	// returned with its status code
+	rc := http.NewResponseController(w)
+	started := false
+	err = h.backend.call(r.Context(), method, parameters, true, func(parameters json.RawMessage) error {
+		if !started {
+			w.Header().Set("Content-Type", s.contentType)
+			w.Header().Set("Cache-Control", "no-cache")
+			started = true
+		}
+		s.reply(w, compact(parameters))
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		body, code := errorBody(err)
+		if !started {
+			w.Header().Set("Content-Type", s.contentType)
+			w.WriteHeader(code)
+		}
+		s.error(w, body)
+	}
+}
@@ -0,0 +1,185 @@
+package httpgateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/varlink/go/varlink"
+	"github.com/varlink/go/varlink/httpgateway"
+)
+
+// testInterface implements the methods of org.example.gateway.
+type testInterface struct{}
+
+func (s *testInterface) VarlinkDispatch(ctx context.Context, call varlink.Call, methodname string) error {
+	switch methodname {
+	case "Echo":
+		var in struct {
+			Text string `json:"text"`
+		}
+		if err := call.GetParameters(&in); err != nil || in.Text == "" {
+			return call.ReplyInvalidParameter(ctx, "text")
+		}
+		return call.Reply(ctx, &in)
+
+	case "Count":
+		if !call.WantsMore() {
+			return call.ReplyError(ctx, "org.example.gateway.MoreRequired", nil)
+		}
+		for i := 1; i < 3; i++ {
+			if err := call.Stream(ctx, map[string]int{"i": i}); err != nil {
+				return err
+			}
+		}
+		return call.ReplyError(ctx, "org.example.gateway.Exhausted", map[string]int{"count": 2})
+
+	case "Ping":
+		return call.Reply(ctx, nil)
+
+	case "Missing":
+		return call.ReplyMethodNotImplemented(ctx, "org.example.gateway.Missing")
+	}
+
+	return call.ReplyMethodNotFound(ctx, methodname)
+}
+
+func (s *testInterface) VarlinkGetName() string {
+	return `org.example.gateway`
+}
+
+func (s *testInterface) VarlinkGetDescription() string {
+	return `interface org.example.gateway
+
+method Echo(text: string) -> (text: string)
+method Count() -> (i: int)
+method Ping() -> ()
+method Missing() -> ()
+
+error MoreRequired ()
+error Exhausted (count: int)
+`
+}
+
+func newService(t *testing.T) *varlink.Service {
+	service, err := varlink.NewService("Varlink", "Gateway Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(new(testInterface)); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+	return service
+}
+
+func TestGateway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	remote := newService(t)
+	if err := remote.Bind(ctx, "tcp:127.0.0.1:0"); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+	l, _ := remote.GetListener()
+	go remote.DoListen(ctx, 0)
+	defer remote.Shutdown()
+
+	conn, err := varlink.NewConnection(ctx, "tcp:"+l.Addr().String())
+	if err != nil {
+		t.Fatalf("NewConnection(): %v", err)
+	}
+	defer conn.Close()
+
+	handlers := map[string]http.Handler{
+		"Service":    httpgateway.NewServiceHandler(newService(t)),
+		"Connection": httpgateway.NewConnectionHandler(conn),
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		accept string
+		body   string
+		status int
+		reply  string
+	}{
+		{"Reply", "POST", "/org.example.gateway/Echo", "", `{"text":"hello"}`, 200, `{"text":"hello"}` + "\n"},
+		{"EmptyReply", "POST", "/org.example.gateway/Ping", "", "", 200, "{}\n"},
+		{"ServiceInterface", "POST", "/org.varlink.service/GetInterfaceDescription", "", `{"interface":"org.example.gateway"}`, 200, `{"description":"interface org.example.gateway`},
+		{"InvalidParameter", "POST", "/org.example.gateway/Echo", "", `{}`, 400,
+			`{"error":"org.varlink.service.InvalidParameter","parameters":{"parameter":"text"}}` + "\n"},
+		{"InvalidBody", "POST", "/org.example.gateway/Echo", "", `["text"]`, 400,
+			`{"error":"org.varlink.service.InvalidParameter","parameters":{"parameter":"parameters"}}` + "\n"},
+		{"InterfaceNotFound", "POST", "/org.example.unknown/Echo", "", "", 404,
+			`{"error":"org.varlink.service.InterfaceNotFound","parameters":{"interface":"org.example.unknown"}}` + "\n"},
+		{"MethodNotFound", "POST", "/org.example.gateway/Unknown", "", "", 404,
+			`{"error":"org.varlink.service.MethodNotFound","parameters":{"method":"Unknown"}}` + "\n"},
+		{"InvalidPath", "POST", "/org.example.gateway", "", "", 404,
+			`{"error":"org.varlink.service.MethodNotFound","parameters":{"method":"org.example.gateway"}}` + "\n"},
+		{"MethodNotImplemented", "POST", "/org.example.gateway/Missing", "", "", 501,
+			`{"error":"org.varlink.service.MethodNotImplemented","parameters":{"method":"org.example.gateway.Missing"}}` + "\n"},
+		{"OtherError", "POST", "/org.example.gateway/Count", "", "", 500,
+			`{"error":"org.example.gateway.MoreRequired"}` + "\n"},
+		{"MethodNotAllowed", "GET", "/org.example.gateway/Ping", "", "", 405, "Method not allowed\n"},
+		{"NDJSON", "POST", "/org.example.gateway/Count", "application/x-ndjson", "", 200,
+			`{"i":1}` + "\n" + `{"i":2}` + "\n" + `{"error":"org.example.gateway.Exhausted","parameters":{"count":2}}` + "\n"},
+		{"SSE", "POST", "/org.example.gateway/Count", "text/html, text/event-stream", "", 200,
+			"data: {\"i\":1}\n\ndata: {\"i\":2}\n\nevent: error\ndata: {\"error\":\"org.example.gateway.Exhausted\",\"parameters\":{\"count\":2}}\n\n"},
+		{"StreamError", "POST", "/org.example.gateway/Missing", "application/x-ndjson", "", 501,
+			`{"error":"org.varlink.service.MethodNotImplemented","parameters":{"method":"org.example.gateway.Missing"}}` + "\n"},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+					r.Header.Set("Content-Type", "application/json")
+					if tt.accept != "" {
+						r.Header.Set("Accept", tt.accept)
+					}
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, r)
+
+					if w.Code != tt.status {
+						t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+					}
+					if !strings.HasPrefix(w.Body.String(), tt.reply) {
+						t.Fatalf("Expected `%s`, got `%s`", tt.reply, w.Body)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	handler := httpgateway.NewServiceHandler(newService(t))
+
+	for _, tt := range []struct {
+		contentType string
+		status      int
+	}{
+		{"application/json", 200},
+		{"Application/JSON; charset=utf-8", 200},
+		{"", 415},
+		{"text/plain", 415},
+		{"application/x-www-form-urlencoded", 415},
+		{"multipart/form-data; boundary=x", 415},
+		{"application/json;;", 415},
+	} {
+		r := httptest.NewRequest("POST", "/org.example.gateway/Ping", nil)
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Fatalf("Content-Type '%s': expected status %d, got %d: %s", tt.contentType, tt.status, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package idl
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// clearPositions removes the source positions from a parsed interface, to
+// compare the parse results of different descriptions.
+func clearPositions(midl *IDL) {
+	midl.Description = ""
+	Inspect(midl, func(n Node) bool {
+		switch n := n.(type) {
+		case *IDL:
+			n.Span, n.NamePos = Span{}, Position{}
+		case *Alias:
+			n.Span, n.NamePos = Span{}, Position{}
+		case *Method:
+			n.Span, n.NamePos = Span{}, Position{}
+		case *Error:
+			n.Span, n.NamePos = Span{}, Position{}
+		case *Type:
+			n.Span = Span{}
+		case *TypeField:
+			n.Span = Span{}
+		}
+		return true
+	})
+}
+
+// testRoundTrip checks that the canonical form of a parsed interface parses
+// to the same interface, and is stable.
+func testRoundTrip(t *testing.T, midl *IDL) {
+	formatted := midl.String()
+
+	again, err := New(formatted)
+	if err != nil {
+		t.Fatalf("New(`%s`): %v", formatted, err)
+	}
+	expect(t, formatted, again.String())
+
+	clearPositions(midl)
+	clearPositions(again)
+	if !reflect.DeepEqual(midl, again) {
+		t.Fatalf("Parsing `%s` returned a different interface", formatted)
+	}
+}
+
+func readCorpus(t testing.TB, dir string) map[string]string {
+	files, err := filepath.Glob(filepath.Join("testdata", dir, "*.varlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corpus := make(map[string]string)
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		corpus[filepath.Base(file)] = string(b)
+	}
+	return corpus
+}
+
+func TestValidCorpus(t *testing.T) {
+	for name, description := range readCorpus(t, "valid") {
+		t.Run(name, func(t *testing.T) {
+			midl, err := New(description)
+			if err != nil {
+				t.Fatalf("New(): %v", err)
+			}
+			testRoundTrip(t, midl)
+		})
+	}
+}
+
+// invalidCorpus maps the files of the invalid corpus to the expected error.
+var invalidCorpus = map[string]string{
+	"comment-only.varlink":              "missing interface keyword",
+	"duplicate-field.varlink":           "missing method input",
+	"duplicate-member.varlink":          "error `F` already defined",
+	"empty.varlink":                     "missing interface keyword",
+	"error-enum.varlink":                "missing error type",
+	"error-lowercase.varlink":           "missing error name",
+	"error-no-type.varlink":             "missing error type",
+	"field-double-underscore.varlink":   "missing method input",
+	"field-trailing-underscore.varlink": "missing method input",
+	"interface-name-hyphen.varlink":     "interface name",
+	"interface-name-uppercase.varlink":  "interface name",
+	"interface-no-space.varlink":        "missing interface keyword",
+	"keyword-no-space.varlink":          "unknown keyword 'method'",
+	"map-int-key.varlink":               "missing method input",
+	"maybe-maybe.varlink":               "missing method input",
+	"method-alias-input.varlink":        "missing method input",
+	"method-arrow.varlink":              "missing method '->' operator",
+	"method-enum-input.varlink":         "missing method input",
+	"method-lowercase.varlink":          "missing method type",
+	"method-scalar-output.varlink":      "missing method output",
+	"mixed-enum-typed-2.varlink":        "missing type declaration",
+	"mixed-enum-typed.varlink":          "missing method input",
+	"no-methods.varlink":                "no methods defined",
+	"type-array.varlink":                "type `T` is not a struct or enum",
+	"type-digit.varlink":                "missing type name",
+	"type-lowercase.varlink":            "missing type name",
+	"type-string.varlink":               "type `T` is not a struct or enum",
+	"unknown-keyword.varlink":           "unknown keyword 'enum'",
+	"unterminated-struct.varlink":       "missing method input",
+}
+
+func TestInvalidCorpus(t *testing.T) {
+	corpus := readCorpus(t, "invalid")
+	for name := range invalidCorpus {
+		if _, ok := corpus[name]; !ok {
+			t.Fatalf("Missing corpus file %s", name)
+		}
+	}
+
+	for name, description := range corpus {
+		t.Run(name, func(t *testing.T) {
+			expected, ok := invalidCorpus[name]
+			if !ok {
+				t.Fatal("No expected error")
+			}
+			_, err := New(description)
+			if err == nil {
+				t.Fatal("New() did not fail")
+			}
+			expect(t, expected, err.Error())
+		})
+	}
+}
+
+func FuzzNew(f *testing.F) {
+	for _, dir := range []string{"valid", "invalid"} {
+		for _, description := range readCorpus(f, dir) {
+			f.Add(description)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, description string) {
+		midl, err := New(description)
+		if err != nil {
+			return
+		}
+		testRoundTrip(t, midl)
+	})
+}
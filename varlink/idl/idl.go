@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // Valid TypeKind values.
@@ -25,75 +27,272 @@ const (
 // TypeKind specifies the type of an Type.
 type TypeKind uint
 
+// Position describes a location in the interface description.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number, starting at 1 (byte count)
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
+// Span is the part of the interface description covered by a node.
+type Span struct {
+	StartPos Position
+	EndPos   Position
+}
+
+// Pos returns the position of the first byte of the node.
+func (s Span) Pos() Position {
+	return s.StartPos
+}
+
+// End returns the position of the byte immediately after the node.
+func (s Span) End() Position {
+	return s.EndPos
+}
+
+// Node is implemented by all nodes of a parsed interface description:
+// *IDL, *Alias, *Method, *Error, *Type and *TypeField.
+type Node interface {
+	Pos() Position
+	End() Position
+}
+
+// Member is implemented by the nodes which can be members of an interface:
+// *Alias, *Method and *Error.
+type Member interface {
+	Node
+	memberNode()
+}
+
 // Type represents a varlink type. Types are method input and output parameters,
 // error output parameters, or custom defined types in the interface description.
 type Type struct {
+	Span
 	Kind        TypeKind
 	ElementType *Type
 	Alias       string
 	Fields      []TypeField
 }
 
+// String returns the type in varlink interface description syntax.
+func (t *Type) String() string {
+	switch t.Kind {
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeObject:
+		return "object"
+	case TypeArray:
+		return "[]" + t.ElementType.String()
+	case TypeMap:
+		return "[string]" + t.ElementType.String()
+	case TypeMaybe:
+		return "?" + t.ElementType.String()
+	case TypeAlias:
+		return t.Alias
+	}
+
+	var b strings.Builder
+	b.WriteString("(")
+	for i, f := range t.Fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.Name)
+		if f.Type != nil {
+			b.WriteString(": " + f.Type.String())
+		}
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
 // TypeField is a named member of a TypeStruct.
 type TypeField struct {
+	Span
 	Name string
 	Type *Type
 }
 
 // Alias represents a named Type in the interface description.
 type Alias struct {
-	Name string
-	Doc  string
-	Type *Type
+	Span
+	NamePos Position
+	Name    string
+	Doc     string
+	Type    *Type
 }
 
 // Method represents a method defined in the interface description.
 type Method struct {
-	Name string
-	Doc  string
-	In   *Type
-	Out  *Type
+	Span
+	NamePos Position
+	Name    string
+	Doc     string
+	In      *Type
+	Out     *Type
 }
 
 // Error represents an error defined in the interface description.
 type Error struct {
-	Name string
-	Doc  string
-	Type *Type
+	Span
+	NamePos Position
+	Name    string
+	Doc     string
+	Type    *Type
 }
 
+func (*Alias) memberNode()  {}
+func (*Method) memberNode() {}
+func (*Error) memberNode()  {}
+
 // IDL represents a parsed varlink interface description with types, methods, errors and
 // documentation.
 type IDL struct {
+	Span
+	NamePos     Position
 	Name        string
 	Doc         string
 	Description string
-	Members     []interface{}
+	Members     []Member
 	Aliases     []*Alias
 	Methods     []*Method
 	Errors      []*Error
 }
 
+// writeDoc writes documentation as comment lines.
+func writeDoc(b *strings.Builder, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			b.WriteString("#\n")
+		} else {
+			b.WriteString("# " + line + "\n")
+		}
+	}
+}
+
+// String returns the interface description in canonical form, including the
+// documentation of the interface and its members. Parsing the result yields
+// the same interface.
+func (idl *IDL) String() string {
+	var b strings.Builder
+
+	writeDoc(&b, idl.Doc)
+	b.WriteString("interface " + idl.Name + "\n")
+
+	for _, member := range idl.Members {
+		b.WriteString("\n")
+		switch m := member.(type) {
+		case *Alias:
+			writeDoc(&b, m.Doc)
+			b.WriteString("type " + m.Name + " " + m.Type.String() + "\n")
+		case *Method:
+			writeDoc(&b, m.Doc)
+			b.WriteString("method " + m.Name + m.In.String() + " -> " + m.Out.String() + "\n")
+		case *Error:
+			writeDoc(&b, m.Doc)
+			b.WriteString("error " + m.Name + " " + m.Type.String() + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// LookupType returns the type alias with the given name, or nil if the
+// interface does not define it.
+func (idl *IDL) LookupType(name string) *Alias {
+	for _, a := range idl.Aliases {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// LookupMethod returns the method with the given name, or nil if the
+// interface does not define it.
+func (idl *IDL) LookupMethod(name string) *Method {
+	for _, m := range idl.Methods {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// LookupError returns the error with the given name, or nil if the
+// interface does not define it.
+func (idl *IDL) LookupError(name string) *Error {
+	for _, e := range idl.Errors {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
 type parser struct {
 	input       string
 	position    int
+	width       int
 	lineStart   int
+	lines       []int
 	lastComment bytes.Buffer
 }
 
-func (p *parser) next() int {
-	r := -1
+// pos returns the Position of a byte offset in the input.
+func (p *parser) pos(offset int) Position {
+	if p.lines == nil {
+		p.lines = []int{0}
+		for i := 0; i < len(p.input); i++ {
+			if p.input[i] == '\n' {
+				p.lines = append(p.lines, i+1)
+			}
+		}
+	}
 
-	if p.position < len(p.input) {
-		r = int(p.input[p.position])
+	line := sort.Search(len(p.lines), func(i int) bool { return p.lines[i] > offset })
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: offset - p.lines[line-1] + 1,
 	}
+}
 
+// span returns the Span from the byte offset start to the current position.
+func (p *parser) span(start int) Span {
+	return Span{StartPos: p.pos(start), EndPos: p.pos(p.position)}
+}
+
+// next returns the next byte of the input, or -1 at the end of the input.
+func (p *parser) next() int {
+	if p.position >= len(p.input) {
+		p.width = 0
+		return -1
+	}
+
+	r := int(p.input[p.position])
+	p.width = 1
 	p.position++
 	return r
 }
 
+// backup steps back over the byte returned by the last call to next.
 func (p *parser) backup() {
-	p.position--
+	p.position -= p.width
+	p.width = 0
 }
 
 func (p *parser) advance() bool {
@@ -108,7 +307,6 @@ func (p *parser) advance() bool {
 			// ignore
 
 		} else if char == '#' {
-			p.next()
 			start := p.position
 			for {
 				c := p.next()
@@ -117,11 +315,23 @@ func (p *parser) advance() bool {
 					break
 				}
 			}
-			if p.lastComment.Len() > 0 {
-				p.lastComment.WriteByte('\n')
+			comment := strings.TrimPrefix(p.input[start:p.position], " ")
+			comment = strings.TrimRight(comment, "\r")
+
+			// Only comments on their own lines document the following member
+			if strings.TrimSpace(p.input[p.lineStart:start-1]) != "" {
+				p.lastComment.Reset()
+			} else {
+				if p.lastComment.Len() > 0 {
+					p.lastComment.WriteByte('\n')
+				}
+				p.lastComment.WriteString(comment)
+			}
+
+			// The end of a comment line does not end the documentation
+			if p.next() == '\n' {
+				p.lineStart = p.position
 			}
-			p.lastComment.WriteString(p.input[start:p.position])
-			p.next()
 
 		} else {
 			p.backup()
@@ -132,16 +342,6 @@ func (p *parser) advance() bool {
 	return p.position < len(p.input)
 }
 
-func (p *parser) advanceOnLine() {
-	for {
-		char := p.next()
-		if char != ' ' {
-			p.backup()
-			return
-		}
-	}
-}
-
 func (p *parser) readKeyword() string {
 	start := p.position
 
@@ -156,27 +356,31 @@ func (p *parser) readKeyword() string {
 	return p.input[start:p.position]
 }
 
+var (
+	domainName              = regexp.MustCompile(`^[a-z]+(\.[a-zA-Z0-9]+([-][a-zA-Z0-9]+)*)+`)
+	internationalDomainName = regexp.MustCompile(`^xn--[a-z0-9]+(\.[a-z0-9]+([-][a-z0-9]+)*)+`)
+)
+
 func (p *parser) readInterfaceName() string {
-	start := p.position
-	dnrx := regexp.MustCompile(`^[a-zA-Z]+(\.[a-zA-Z0-9]+([-][a-zA-Z0-9]+)*)+`)
-	name := dnrx.FindString(p.input[start:])
-	if name != "" {
-		if len(name) > 255 {
-			return ""
-		}
-		p.position += len(name)
-		return name
-	}
-	xdnrx := regexp.MustCompile(`^xn--[a-z0-9]+(\.[a-z0-9]+([-][a-z0-9]+)*)+`)
-	name = xdnrx.FindString(p.input[start:])
-	if name != "" {
-		if len(name) > 255 {
-			return ""
-		}
-		p.position += len(name)
-		return name
+	name := domainName.FindString(p.input[p.position:])
+	if name == "" {
+		name = internationalDomainName.FindString(p.input[p.position:])
+	}
+	if name == "" || len(name) > 255 {
+		return ""
+	}
+
+	p.position += len(name)
+	return name
+}
+
+// atSeparator reports whether the input continues with white space, a
+// comment or ends, as required after keywords and the interface name.
+func (p *parser) atSeparator() bool {
+	if p.position >= len(p.input) {
+		return true
 	}
-	return ""
+	return strings.IndexByte(" \t\r\n#", p.input[p.position]) >= 0
 }
 
 func (p *parser) readFieldName() string {
@@ -190,7 +394,17 @@ func (p *parser) readFieldName() string {
 
 	for {
 		char := p.next()
-		if (char < 'A' || char > 'Z') && (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '_' {
+		if char == '_' {
+			// Underscores separate words, they can not follow each other
+			// or end the name
+			char = p.next()
+			p.backup()
+			if (char < 'A' || char > 'Z') && (char < 'a' || char > 'z') && (char < '0' || char > '9') {
+				return ""
+			}
+			continue
+		}
+		if (char < 'A' || char > 'Z') && (char < 'a' || char > 'z') && (char < '0' || char > '9') {
 			p.backup()
 			break
 		}
@@ -202,6 +416,12 @@ func (p *parser) readFieldName() string {
 func (p *parser) readTypeName() string {
 	start := p.position
 
+	char := p.next()
+	if char < 'A' || char > 'Z' {
+		p.backup()
+		return ""
+	}
+
 	for {
 		char := p.next()
 		if (char < 'A' || char > 'Z') && (char < 'a' || char > 'z') && (char < '0' || char > '9') {
@@ -221,6 +441,7 @@ func (p *parser) readStructType() *Type {
 
 	t := &Type{Kind: TypeStruct}
 	t.Fields = make([]TypeField, 0)
+	names := make(map[string]struct{})
 
 	char := p.next()
 	if char != ')' {
@@ -230,10 +451,15 @@ func (p *parser) readStructType() *Type {
 			field := TypeField{}
 
 			p.advance()
+			start := p.position
 			field.Name = p.readFieldName()
 			if field.Name == "" {
 				return nil
 			}
+			if _, ok := names[field.Name]; ok {
+				return nil
+			}
+			names[field.Name] = struct{}{}
 
 			p.advance()
 
@@ -249,9 +475,16 @@ func (p *parser) readStructType() *Type {
 					return nil
 				}
 
+				field.Span = p.span(start)
+
 			} else {
+				// Enums can not be mixed with typed fields
+				if t.Kind != TypeEnum && len(t.Fields) > 0 {
+					return nil
+				}
 				t.Kind = TypeEnum
 				p.backup()
+				field.Span = Span{StartPos: p.pos(start), EndPos: p.pos(start + len(field.Name))}
 			}
 
 			t.Fields = append(t.Fields, field)
@@ -273,6 +506,7 @@ func (p *parser) readStructType() *Type {
 
 func (p *parser) readType() *Type {
 	var t *Type
+	start := p.position
 
 	switch p.next() {
 	case '?':
@@ -336,14 +570,18 @@ func (p *parser) readType() *Type {
 		}
 	}
 
+	if t != nil {
+		t.Span = p.span(start)
+	}
 	return t
 }
 
-func (p *parser) readAlias(idl *IDL) (*Alias, error) {
+func (p *parser) readAlias(idl *IDL, start int) (*Alias, error) {
 	a := &Alias{}
 
-	p.advance()
 	a.Doc = p.lastComment.String()
+	p.advance()
+	a.NamePos = p.pos(p.position)
 	a.Name = p.readTypeName()
 	if a.Name == "" {
 		return nil, fmt.Errorf("missing type name")
@@ -354,15 +592,20 @@ func (p *parser) readAlias(idl *IDL) (*Alias, error) {
 	if a.Type == nil {
 		return nil, fmt.Errorf("missing type declaration")
 	}
+	if a.Type.Kind != TypeStruct && a.Type.Kind != TypeEnum {
+		return nil, fmt.Errorf("type `%s` is not a struct or enum", a.Name)
+	}
 
+	a.Span = p.span(start)
 	return a, nil
 }
 
-func (p *parser) readMethod(idl *IDL) (*Method, error) {
+func (p *parser) readMethod(idl *IDL, start int) (*Method, error) {
 	m := &Method{}
 
-	p.advance()
 	m.Doc = p.lastComment.String()
+	p.advance()
+	m.NamePos = p.pos(p.position)
 	m.Name = p.readTypeName()
 	if m.Name == "" {
 		return nil, fmt.Errorf("missing method type")
@@ -370,7 +613,7 @@ func (p *parser) readMethod(idl *IDL) (*Method, error) {
 
 	p.advance()
 	m.In = p.readType()
-	if m.In == nil {
+	if m.In == nil || m.In.Kind != TypeStruct {
 		return nil, fmt.Errorf("missing method input")
 	}
 
@@ -383,47 +626,57 @@ func (p *parser) readMethod(idl *IDL) (*Method, error) {
 
 	p.advance()
 	m.Out = p.readType()
-	if m.Out == nil {
+	if m.Out == nil || m.Out.Kind != TypeStruct {
 		return nil, fmt.Errorf("missing method output")
 	}
 
+	m.Span = p.span(start)
 	return m, nil
 }
 
-func (p *parser) readError(idl *IDL) (*Error, error) {
+func (p *parser) readError(idl *IDL, start int) (*Error, error) {
 	e := &Error{}
 
-	p.advance()
 	e.Doc = p.lastComment.String()
+	p.advance()
+	e.NamePos = p.pos(p.position)
 	e.Name = p.readTypeName()
 	if e.Name == "" {
 		return nil, fmt.Errorf("missing error name")
 	}
 
-	p.advanceOnLine()
+	p.advance()
 	e.Type = p.readType()
+	if e.Type == nil || e.Type.Kind != TypeStruct {
+		return nil, fmt.Errorf("missing error type")
+	}
 
+	e.Span = p.span(start)
 	return e, nil
 }
 
 func (p *parser) readIDL() (*IDL, error) {
-	if keyword := p.readKeyword(); keyword != "interface" {
+	start := p.position
+	doc := p.lastComment.String()
+	if keyword := p.readKeyword(); keyword != "interface" || !p.atSeparator() {
 		return nil, fmt.Errorf("missing interface keyword")
 	}
 
 	idl := &IDL{
-		Members: make([]interface{}, 0),
+		Members: make([]Member, 0),
 		Aliases: make([]*Alias, 0),
 		Methods: make([]*Method, 0),
 		Errors:  make([]*Error, 0),
+		Doc:     doc,
 	}
 
 	p.advance()
-	idl.Doc = p.lastComment.String()
+	idl.NamePos = p.pos(p.position)
 	idl.Name = p.readInterfaceName()
-	if idl.Name == "" {
+	if idl.Name == "" || !p.atSeparator() {
 		return nil, fmt.Errorf("interface name")
 	}
+	idl.Span = p.span(start)
 
 	// Check for duplicates
 	members := make(map[string]struct{}, 0)
@@ -433,9 +686,15 @@ func (p *parser) readIDL() (*IDL, error) {
 			break
 		}
 
-		switch keyword := p.readKeyword(); keyword {
+		memberStart := p.position
+		keyword := p.readKeyword()
+		if !p.atSeparator() {
+			return nil, fmt.Errorf("unknown keyword '%s'", keyword)
+		}
+
+		switch keyword {
 		case "type":
-			a, err := p.readAlias(idl)
+			a, err := p.readAlias(idl, memberStart)
 			if err != nil {
 				return nil, err
 			}
@@ -447,7 +706,7 @@ func (p *parser) readIDL() (*IDL, error) {
 			idl.Members = append(idl.Members, a)
 
 		case "method":
-			m, err := p.readMethod(idl)
+			m, err := p.readMethod(idl, memberStart)
 			if err != nil {
 				return nil, err
 			}
@@ -459,7 +718,7 @@ func (p *parser) readIDL() (*IDL, error) {
 			idl.Members = append(idl.Members, m)
 
 		case "error":
-			e, err := p.readError(idl)
+			e, err := p.readError(idl, memberStart)
 			if err != nil {
 				return nil, err
 			}
@@ -473,6 +732,8 @@ func (p *parser) readIDL() (*IDL, error) {
 		default:
 			return nil, fmt.Errorf("unknown keyword '%s'", keyword)
 		}
+
+		idl.EndPos = p.pos(p.position)
 	}
 
 	return idl, nil
@@ -3,10 +3,10 @@ package idl
 import (
 	"fmt"
 	"runtime"
+	"strings"
 	"testing"
 )
 
-/*
 func expect(t *testing.T, expected string, returned string) {
 	if strings.Compare(returned, expected) != 0 {
 		t.Fatalf("Expected(%d): `%s`\nGot(%d): `%s`\n",
@@ -14,7 +14,6 @@ func expect(t *testing.T, expected string, returned string) {
 			len(returned), returned)
 	}
 }
-*/
 
 func testParse(t *testing.T, pass bool, description string) {
 	_, _, line, _ := runtime.Caller(1)
@@ -50,7 +49,7 @@ func TestDomainNames(t *testing.T) {
 	testParse(t, true, "interface xn--lgbbat1ad8j.example.algeria\nmethod F()->()")
 	testParse(t, false, "interface com.-example.leadinghyphen\nmethod F()->()")
 	testParse(t, false, "interface com.example-.danglinghyphen-\nmethod F()->()")
-	testParse(t, true, "interface Com.example.uppercase-toplevel\nmethod F()->()")
+	testParse(t, false, "interface Com.example.uppercase-toplevel\nmethod F()->()")
 	testParse(t, false, "interface Co9.example.number-toplevel\nmethod F()->()")
 	testParse(t, false, "interface 1om.example.number-toplevel\nmethod F()->()")
 	testParse(t, true, "interface com.Example\nmethod F()->()")
@@ -98,6 +97,30 @@ func TestFieldnames(t *testing.T) {
 	testParse(t, false, "interface foo.bar\n type I (Test:[]bool)\nmethod  F()->()")
 	testParse(t, false, "interface foo.bar\n type I (_test:[]bool)\nmethod  F()->()")
 	testParse(t, false, "interface foo.bar\n type I (Äest:[]bool)\nmethod  F()->()")
+	testParse(t, true, "interface foo.bar\n type I (test_1_a:[]bool)\nmethod  F()->()")
+	testParse(t, false, "interface foo.bar\n type I (test__a:[]bool)\nmethod  F()->()")
+	testParse(t, false, "interface foo.bar\n type I (test_:[]bool)\nmethod  F()->()")
+	testParse(t, false, "interface foo.bar\n type I (a:int, a:int)\nmethod  F()->()")
+}
+
+func TestMemberNames(t *testing.T) {
+	testParse(t, true, "interface foo.bar\ntype I0 ()\nmethod F1()->()\nerror E2 ()")
+	testParse(t, false, "interface foo.bar\ntype i ()\nmethod F()->()")
+	testParse(t, false, "interface foo.bar\ntype 0I ()\nmethod F()->()")
+	testParse(t, false, "interface foo.bar\nmethod f()->()")
+	testParse(t, false, "interface foo.bar\nmethod F()->()\nerror e ()")
+}
+
+func TestMemberTypes(t *testing.T) {
+	testParse(t, true, "interface foo.bar\ntype I (a, b)\nmethod F()->()")
+	testParse(t, false, "interface foo.bar\ntype I []string\nmethod F()->()")
+	testParse(t, false, "interface foo.bar\ntype I ?(a: int)\nmethod F()->()")
+	testParse(t, false, "interface foo.bar\ntype I (a: int)\nmethod F I->()")
+	testParse(t, false, "interface foo.bar\nmethod F(a, b)->()")
+	testParse(t, false, "interface foo.bar\nmethod F()->string")
+	testParse(t, true, "interface foo.bar\nmethod F()->()\nerror E\n  (a: int)")
+	testParse(t, false, "interface foo.bar\nmethod F()->()\nerror E")
+	testParse(t, false, "interface foo.bar\nmethod F()->()\nerror E (a, b)")
 }
 
 func TestNestedStructs(t *testing.T) {
@@ -107,6 +130,8 @@ func TestNestedStructs(t *testing.T) {
 func TestEnum(t *testing.T) {
 	testParse(t, true, "interface foo.bar\n type I (b:(foo, bar, baz))\nmethod  F()->()")
 	testParse(t, false, "interface foo.bar\n type I (foo, bar, baz : bool)\nmethod  F()->()")
+	testParse(t, false, "interface foo.bar\n type I (foo: bool, bar)\nmethod  F()->()")
+	testParse(t, false, "interface foo.bar\n type I (foo, foo)\nmethod  F()->()")
 }
 
 func TestMap(t *testing.T) {
@@ -135,6 +160,11 @@ func TestIncomplete(t *testing.T) {
 	testParse(t, false, "interface foo.bar\nmethod  F()->()\ntype (b: bool)")
 	testParse(t, false, "interface foo.bar\nmethod  F()->()\nerror (b: bool)")
 	testParse(t, false, "interface foo.bar\nmethod  F()->()\n dfghdrg")
+	testParse(t, false, "interfacefoo.bar\nmethod  F()->()")
+	testParse(t, false, "interface foo.bar\nmethodF()->()")
+	testParse(t, false, "interface foo.bar(\nmethod F()->()")
+	testParse(t, false, "#")
+	testParse(t, false, "")
 }
 
 func TestDuplicate(t *testing.T) {
@@ -148,3 +178,56 @@ interface foo.example
 	method F() -> ()
 `)
 }
+
+func TestTypeString(t *testing.T) {
+	description := "interface foo.bar\nmethod F(a: ?[]?[string](x, y), b: (c: int, d: object), e: float, f: []F) -> (g: bool, h: string)"
+	midl, err := New(description)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	m := midl.Methods[0]
+	expect(t, "(a: ?[]?[string](x, y), b: (c: int, d: object), e: float, f: []F)", m.In.String())
+	expect(t, "(g: bool, h: string)", m.Out.String())
+}
+
+func TestDoc(t *testing.T) {
+	description := `# Interface
+#
+#  indented
+interface foo.bar # not documentation
+
+# Unrelated
+
+#First
+# second
+method F() -> () # not documentation
+# G
+method G() -> ()
+`
+	midl, err := New(description)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	expect(t, "Interface\n\n indented", midl.Doc)
+	expect(t, "First\nsecond", midl.Methods[0].Doc)
+	expect(t, "G", midl.Methods[1].Doc)
+}
+
+func TestString(t *testing.T) {
+	description := "# Interface\r\n#\r\ninterface foo.bar\r\ntype T(a:int,b:(c,d))\r\n# F\r\nmethod F(t:T)->()\r\nerror E()"
+	midl, err := New(description)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	expect(t, `# Interface
+#
+interface foo.bar
+
+type T (a: int, b: (c, d))
+
+# F
+method F(t: T) -> ()
+
+error E ()
+`, midl.String())
+}
@@ -0,0 +1,123 @@
+package idl
+
+import (
+	"encoding/json"
+)
+
+// JSONSchemaDialect is the JSON Schema version of the documents generated by
+// JSONSchema.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema document describing the interface. Every
+// alias, every error and the input and output of every method are available
+// as definitions below "$defs", named after the alias, the error, and
+// "<Method>_In" and "<Method>_Out" respectively. Optional types are not
+// required and accept null, doc comments become descriptions.
+func (idl *IDL) JSONSchema() ([]byte, error) {
+	defs := make(map[string]interface{})
+
+	for _, a := range idl.Aliases {
+		defs[a.Name] = describedSchema(a.Type, a.Doc)
+	}
+
+	for _, m := range idl.Methods {
+		defs[m.Name+"_In"] = describedSchema(m.In, m.Doc)
+		defs[m.Name+"_Out"] = describedSchema(m.Out, m.Doc)
+	}
+
+	for _, e := range idl.Errors {
+		t := e.Type
+		if t == nil {
+			t = &Type{Kind: TypeStruct}
+		}
+		defs[e.Name] = describedSchema(t, e.Doc)
+	}
+
+	schema := map[string]interface{}{
+		"$schema": JSONSchemaDialect,
+		"title":   idl.Name,
+		"$defs":   defs,
+	}
+	if idl.Doc != "" {
+		schema["description"] = idl.Doc
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func describedSchema(t *Type, doc string) map[string]interface{} {
+	s := typeSchema(t)
+	if doc != "" {
+		s["description"] = doc
+	}
+	return s
+}
+
+func typeSchema(t *Type) map[string]interface{} {
+	switch t.Kind {
+	case TypeBool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case TypeInt:
+		return map[string]interface{}{"type": "integer"}
+
+	case TypeFloat:
+		return map[string]interface{}{"type": "number"}
+
+	case TypeString:
+		return map[string]interface{}{"type": "string"}
+
+	case TypeObject:
+		// Foreign objects carry any JSON value
+		return map[string]interface{}{}
+
+	case TypeArray:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.ElementType),
+		}
+
+	case TypeMap:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.ElementType),
+		}
+
+	case TypeMaybe:
+		return map[string]interface{}{
+			"anyOf": []interface{}{
+				typeSchema(t.ElementType),
+				map[string]interface{}{"type": "null"},
+			},
+		}
+
+	case TypeAlias:
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Alias}
+
+	case TypeEnum:
+		values := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			values[i] = f.Name
+		}
+		return map[string]interface{}{
+			"type": "string",
+			"enum": values,
+		}
+	}
+
+	properties := make(map[string]interface{}, len(t.Fields))
+	required := make([]string, 0, len(t.Fields))
+	for _, f := range t.Fields {
+		properties[f.Name] = typeSchema(f.Type)
+		if f.Type.Kind != TypeMaybe {
+			required = append(required, f.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
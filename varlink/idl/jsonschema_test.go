@@ -0,0 +1,95 @@
+package idl
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	midl, err := New(`# The example interface
+interface org.example.schema
+
+# A drive
+type Drive (
+  state: (idle, busy),
+  labels: [string]string,
+  note: ?string
+)
+
+# Start the drive
+method Start(drive: Drive, speeds: []?float) -> (started: bool)
+
+error Failed (reason: string, data: object)
+`)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	b, err := midl.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema(): %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("JSONSchema() returned invalid JSON: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"$schema":     JSONSchemaDialect,
+		"title":       "org.example.schema",
+		"description": "The example interface",
+		"$defs": map[string]interface{}{
+			"Drive": map[string]interface{}{
+				"description": "A drive",
+				"type":        "object",
+				"properties": map[string]interface{}{
+					"state":  map[string]interface{}{"type": "string", "enum": []interface{}{"idle", "busy"}},
+					"labels": map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+					"note": map[string]interface{}{"anyOf": []interface{}{
+						map[string]interface{}{"type": "string"},
+						map[string]interface{}{"type": "null"},
+					}},
+				},
+				"required":             []interface{}{"state", "labels"},
+				"additionalProperties": false,
+			},
+			"Start_In": map[string]interface{}{
+				"description": "Start the drive",
+				"type":        "object",
+				"properties": map[string]interface{}{
+					"drive": map[string]interface{}{"$ref": "#/$defs/Drive"},
+					"speeds": map[string]interface{}{"type": "array", "items": map[string]interface{}{"anyOf": []interface{}{
+						map[string]interface{}{"type": "number"},
+						map[string]interface{}{"type": "null"},
+					}}},
+				},
+				"required":             []interface{}{"drive", "speeds"},
+				"additionalProperties": false,
+			},
+			"Start_Out": map[string]interface{}{
+				"description": "Start the drive",
+				"type":        "object",
+				"properties": map[string]interface{}{
+					"started": map[string]interface{}{"type": "boolean"},
+				},
+				"required":             []interface{}{"started"},
+				"additionalProperties": false,
+			},
+			"Failed": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"reason": map[string]interface{}{"type": "string"},
+					"data":   map[string]interface{}{},
+				},
+				"required":             []interface{}{"reason", "data"},
+				"additionalProperties": false,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(schema, expected) {
+		t.Fatalf("JSONSchema() returned unexpected document:\n%s", b)
+	}
+}
@@ -0,0 +1,215 @@
+package idl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AliasResolver looks up the Type of a named type referenced by a TypeAlias.
+type AliasResolver interface {
+	ResolveAlias(name string) *Type
+}
+
+// ResolveAlias returns the Type of the named alias, or nil if the interface
+// description does not define it.
+func (idl *IDL) ResolveAlias(name string) *Type {
+	if a := idl.LookupType(name); a != nil {
+		return a.Type
+	}
+	return nil
+}
+
+// ValidationError describes a value which does not match its Type. Path is a
+// JSON pointer to the offending value, it is empty if the value as a whole
+// is invalid.
+type ValidationError struct {
+	Path   string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Reason
+	}
+	return e.Path + ": " + e.Reason
+}
+
+// Validate checks that the JSON encoded value matches the type. Named types
+// are looked up with the resolver, which may be nil if the type does not
+// reference any. An empty value is treated like JSON null.
+func (t *Type) Validate(value json.RawMessage, resolver AliasResolver) error {
+	var v interface{}
+
+	if len(bytes.TrimSpace(value)) > 0 {
+		d := json.NewDecoder(bytes.NewReader(value))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			return &ValidationError{Reason: fmt.Sprintf("invalid JSON: %v", err)}
+		}
+		if d.More() {
+			return &ValidationError{Reason: "invalid JSON: trailing data"}
+		}
+	}
+
+	return validate(t, v, "", resolver)
+}
+
+func jsonPointer(path string, token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	token = strings.Replace(token, "/", "~1", -1)
+	return path + "/" + token
+}
+
+func kindName(t *Type) string {
+	switch t.Kind {
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeObject:
+		return "object"
+	case TypeArray:
+		return "array"
+	case TypeMap:
+		return "map"
+	case TypeEnum:
+		return "enum"
+	case TypeAlias:
+		return t.Alias
+	}
+	return "struct"
+}
+
+func validate(t *Type, v interface{}, path string, resolver AliasResolver) error {
+	// Follow named types; a chain of aliases must end somewhere.
+	for hops := 0; t.Kind == TypeAlias; hops++ {
+		var a *Type
+		if resolver != nil {
+			a = resolver.ResolveAlias(t.Alias)
+		}
+		if a == nil || hops > 64 {
+			return &ValidationError{Path: path, Reason: fmt.Sprintf("unknown type `%s`", t.Alias)}
+		}
+		t = a
+	}
+
+	if t.Kind == TypeMaybe {
+		if v == nil {
+			return nil
+		}
+		return validate(t.ElementType, v, path, resolver)
+	}
+
+	if v == nil {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("expected %s, got null", kindName(t))}
+	}
+
+	mismatch := func() error {
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("expected %s", kindName(t))}
+	}
+
+	switch t.Kind {
+	case TypeBool:
+		if _, ok := v.(bool); !ok {
+			return mismatch()
+		}
+
+	case TypeInt:
+		n, ok := v.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+		if _, err := strconv.ParseInt(string(n), 10, 64); err != nil {
+			return mismatch()
+		}
+
+	case TypeFloat:
+		if _, ok := v.(json.Number); !ok {
+			return mismatch()
+		}
+
+	case TypeString:
+		if _, ok := v.(string); !ok {
+			return mismatch()
+		}
+
+	case TypeObject:
+		// Foreign objects carry any JSON value.
+
+	case TypeEnum:
+		s, ok := v.(string)
+		if !ok {
+			return mismatch()
+		}
+		for _, f := range t.Fields {
+			if f.Name == s {
+				return nil
+			}
+		}
+		return &ValidationError{Path: path, Reason: fmt.Sprintf("invalid enum value %q", s)}
+
+	case TypeArray:
+		a, ok := v.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for i, e := range a {
+			if err := validate(t.ElementType, e, jsonPointer(path, strconv.Itoa(i)), resolver); err != nil {
+				return err
+			}
+		}
+
+	case TypeMap:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := validate(t.ElementType, m[k], jsonPointer(path, k), resolver); err != nil {
+				return err
+			}
+		}
+
+	case TypeStruct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		known := make(map[string]struct{}, len(t.Fields))
+		for _, f := range t.Fields {
+			known[f.Name] = struct{}{}
+			e, ok := m[f.Name]
+			if !ok && f.Type.Kind != TypeMaybe {
+				return &ValidationError{Path: jsonPointer(path, f.Name), Reason: "missing required field"}
+			}
+			if err := validate(f.Type, e, jsonPointer(path, f.Name), resolver); err != nil {
+				return err
+			}
+		}
+		var unknown []string
+		for k := range m {
+			if _, ok := known[k]; !ok {
+				unknown = append(unknown, k)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return &ValidationError{Path: jsonPointer(path, unknown[0]), Reason: "unknown field"}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package idl
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	midl, err := New(`
+interface org.example.validate
+
+type State (idle, busy)
+
+type Item (
+  id: int,
+  state: State,
+  tags: [string]string,
+  note: ?string
+)
+
+method Set(
+  item: Item,
+  ratio: float,
+  data: object,
+  items: ?[]Item
+) -> ()
+`)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	in := midl.Methods[0].In
+
+	tests := []struct {
+		name  string
+		pass  bool
+		value string
+		path  string
+	}{
+		{"Valid", true, `{"item": {"id": 1, "state": "idle", "tags": {}}, "ratio": 1, "data": [1, "x"]}`, ""},
+		{"ValidMaybe", true, `{"item": {"id": 1, "state": "busy", "tags": {"a": "b"}, "note": null}, "ratio": 0.5, "data": {}, "items": null}`, ""},
+		{"IntNotFloat", false, `{"item": {"id": 1.5, "state": "idle", "tags": {}}, "ratio": 1, "data": {}}`, "/item/id"},
+		{"FloatNotString", false, `{"item": {"id": 1, "state": "idle", "tags": {}}, "ratio": "1", "data": {}}`, "/ratio"},
+		{"EnumValue", false, `{"item": {"id": 1, "state": "gone", "tags": {}}, "ratio": 1, "data": {}}`, "/item/state"},
+		{"Required", false, `{"item": {"id": 1, "state": "idle"}, "ratio": 1, "data": {}}`, "/item/tags"},
+		{"RequiredObject", false, `{"item": {"id": 1, "state": "idle", "tags": {}}, "ratio": 1}`, "/data"},
+		{"NullObject", false, `{"item": {"id": 1, "state": "idle", "tags": {}}, "ratio": 1, "data": null}`, "/data"},
+		{"MapValue", false, `{"item": {"id": 1, "state": "idle", "tags": {"a/b": 1}}, "ratio": 1, "data": {}}`, "/item/tags/a~1b"},
+		{"ArrayElement", false, `{"item": {"id": 1, "state": "idle", "tags": {}}, "ratio": 1, "data": {}, "items": [{"id": 1, "state": "idle", "tags": {}}, {"id": "2"}]}`, "/items/1/id"},
+		{"UnknownField", false, `{"item": {"id": 1, "state": "idle", "tags": {}, "extra": true}, "ratio": 1, "data": {}}`, "/item/extra"},
+		{"NotStruct", false, `[]`, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := in.Validate(json.RawMessage(test.value), midl)
+			if test.pass {
+				if err != nil {
+					t.Fatalf("Validate(): %v", err)
+				}
+				return
+			}
+			verr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Validate() returned %v, expected a ValidationError", err)
+			}
+			if verr.Path != test.path {
+				t.Fatalf("Validate() returned path `%s`, expected `%s`", verr.Path, test.path)
+			}
+		})
+	}
+}
+
+func TestValidateUnknownAlias(t *testing.T) {
+	typ := &Type{Kind: TypeAlias, Alias: "Missing"}
+	if err := typ.Validate(json.RawMessage(`{}`), nil); err == nil {
+		t.Fatal("Validate() accepted an unresolved type")
+	}
+}
+
+func TestValidateInvalidJSON(t *testing.T) {
+	typ := &Type{Kind: TypeString}
+	if err := typ.Validate(json.RawMessage(`"a" "b"`), nil); err == nil {
+		t.Fatal("Validate() accepted trailing data")
+	}
+	if err := typ.Validate(json.RawMessage(`{`), nil); err == nil {
+		t.Fatal("Validate() accepted invalid JSON")
+	}
+}
@@ -0,0 +1,78 @@
+package idl
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an interface description in depth-first order: It starts by
+// calling v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// The members of an IDL are visited in the order of the description, the
+// fields of a Type are visited as *TypeField.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *IDL:
+		for _, m := range n.Members {
+			Walk(v, m)
+		}
+
+	case *Alias:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+
+	case *Method:
+		if n.In != nil {
+			Walk(v, n.In)
+		}
+		if n.Out != nil {
+			Walk(v, n.Out)
+		}
+
+	case *Error:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+
+	case *Type:
+		if n.ElementType != nil {
+			Walk(v, n.ElementType)
+		}
+		for i := range n.Fields {
+			Walk(v, &n.Fields[i])
+		}
+
+	case *TypeField:
+		if n.Type != nil {
+			Walk(v, n.Type)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an interface description in depth-first order: It starts
+// by calling f(node); node must not be nil. If f returns true, Inspect invokes
+// f recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,137 @@
+package idl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const walkDescription = `# Example
+interface org.example.walk
+
+type State (idle, busy)
+
+# Get the state
+method Get(id: int) -> (state: ?State)
+
+error Failed (reason: string)
+`
+
+func TestPositions(t *testing.T) {
+	midl, err := New(walkDescription)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	text := func(n Node) string {
+		return walkDescription[n.Pos().Offset:n.End().Offset]
+	}
+
+	expect(t, "interface org.example.walk\n\ntype State (idle, busy)\n\n# Get the state\nmethod Get(id: int) -> (state: ?State)\n\nerror Failed (reason: string)", text(midl))
+	expect(t, "2:11", midl.NamePos.String())
+
+	a := midl.LookupType("State")
+	expect(t, "type State (idle, busy)", text(a))
+	expect(t, "4:1", a.Pos().String())
+	expect(t, "4:6", a.NamePos.String())
+	expect(t, "busy", text(&a.Type.Fields[1]))
+
+	m := midl.LookupMethod("Get")
+	expect(t, "method Get(id: int) -> (state: ?State)", text(m))
+	expect(t, "7:1", m.Pos().String())
+	expect(t, "7:8", m.NamePos.String())
+	expect(t, "(id: int)", text(m.In))
+	expect(t, "id: int", text(&m.In.Fields[0]))
+	expect(t, "?State", text(m.Out.Fields[0].Type))
+	expect(t, "State", text(m.Out.Fields[0].Type.ElementType))
+	expect(t, "7:33", m.Out.Fields[0].Type.ElementType.Pos().String())
+	expect(t, "7:38", m.Out.Fields[0].Type.ElementType.End().String())
+
+	e := midl.LookupError("Failed")
+	expect(t, "error Failed (reason: string)", text(e))
+	expect(t, "(reason: string)", text(e.Type))
+
+	if midl.LookupMethod("Set") != nil || midl.LookupType("Get") != nil || midl.LookupError("State") != nil {
+		t.Fatal("Lookup returned a member of a different kind or name")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	midl, err := New(walkDescription)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	var visited []string
+	Inspect(midl, func(n Node) bool {
+		switch n := n.(type) {
+		case *IDL:
+			visited = append(visited, "interface "+n.Name)
+		case *Alias:
+			visited = append(visited, "type "+n.Name)
+		case *Method:
+			visited = append(visited, "method "+n.Name)
+			// Skip the method output
+			Inspect(n.In, func(n Node) bool {
+				if f, ok := n.(*TypeField); ok {
+					visited = append(visited, "in "+f.Name)
+				}
+				return true
+			})
+			return false
+		case *Error:
+			visited = append(visited, "error "+n.Name)
+		case *TypeField:
+			visited = append(visited, "field "+n.Name)
+		case *Type:
+			visited = append(visited, fmt.Sprintf("kind %d", n.Kind))
+		}
+		return true
+	})
+
+	expect(t, strings.Join([]string{
+		"interface org.example.walk",
+		"type State", "kind 9", "field idle", "field busy",
+		"method Get", "in id",
+		"error Failed", "kind 8", "field reason", "kind 3",
+	}, ", "), strings.Join(visited, ", "))
+}
+
+type countingVisitor map[string]int
+
+func (v countingVisitor) Visit(n Node) Visitor {
+	if n == nil {
+		v["nil"]++
+		return nil
+	}
+	v[fmt.Sprintf("%T", n)]++
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	midl, err := New(walkDescription)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	v := countingVisitor{}
+	Walk(v, midl)
+
+	expected := countingVisitor{
+		"*idl.IDL":       1,
+		"*idl.Alias":     1,
+		"*idl.Method":    1,
+		"*idl.Error":     1,
+		"*idl.Type":      8,
+		"*idl.TypeField": 5,
+	}
+	for k, n := range expected {
+		if v[k] != n {
+			t.Fatalf("Walk visited %d nodes of %s, expected %d", v[k], k, n)
+		}
+	}
+	// Every visited node is followed by a call of Visit(nil)
+	if v["nil"] != 17 {
+		t.Fatalf("Walk called Visit(nil) %d times, expected 17", v["nil"])
+	}
+}
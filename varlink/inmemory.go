@@ -0,0 +1,169 @@
+package varlink
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/varlink/go/varlink/internal/ctxio"
+)
+
+// inMemoryBacklog is the number of connections, which can be dialled before
+// they are accepted.
+const inMemoryBacklog = 16
+
+var _ net.Listener = &InMemoryListener{}
+var _ ContextDialer = &InMemoryListener{}
+
+// InMemoryListener is a net.Listener for connections within the process,
+// which do not need any socket. Every connection is a synchronous, in-memory
+// pipe with deadline support, like the ones returned by net.Pipe. It can be
+// used to test a service, or to embed a service into a program:
+//
+//	l := varlink.NewInMemoryListener()
+//	go service.Serve(ctx, l, 0)
+//	conn, err := varlink.NewInMemoryConnection(ctx, l)
+type InMemoryListener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+
+	mutex    sync.Mutex
+	deadline time.Time
+	changed  chan struct{}
+}
+
+// NewInMemoryListener returns a new InMemoryListener.
+func NewInMemoryListener() *InMemoryListener {
+	return &InMemoryListener{
+		conns:   make(chan net.Conn, inMemoryBacklog),
+		done:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+func (l *InMemoryListener) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: l.Addr().Network(), Addr: l.Addr(), Err: err}
+}
+
+// Accept waits for and returns the next connection.
+func (l *InMemoryListener) Accept() (net.Conn, error) {
+	for {
+		l.mutex.Lock()
+		deadline, changed := l.deadline, l.changed
+		l.mutex.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return nil, l.opError("accept", os.ErrDeadlineExceeded)
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+
+		conn, err := l.accept(timeout, changed)
+		if timer != nil {
+			timer.Stop()
+		}
+		if conn != nil || err != nil {
+			return conn, err
+		}
+	}
+}
+
+// accept waits for the next connection, until the listener is closed, the
+// timeout expires or the deadline is changed. It returns nil without error
+// on a changed deadline.
+func (l *InMemoryListener) accept(timeout <-chan time.Time, changed chan struct{}) (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, l.opError("accept", net.ErrClosed)
+	case <-timeout:
+		return nil, l.opError("accept", os.ErrDeadlineExceeded)
+	case <-changed:
+		return nil, nil
+	}
+}
+
+// SetDeadline sets the deadline for Accept. A zero value disables the
+// deadline.
+func (l *InMemoryListener) SetDeadline(t time.Time) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.deadline = t
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return nil
+}
+
+// Close closes the listener and all connections, which are not accepted yet.
+func (l *InMemoryListener) Close() error {
+	err := l.opError("close", net.ErrClosed)
+	l.once.Do(func() {
+		close(l.done)
+		err = nil
+	})
+
+	for {
+		select {
+		case conn := <-l.conns:
+			conn.Close()
+		default:
+			return err
+		}
+	}
+}
+
+// Addr returns the address of the listener.
+func (l *InMemoryListener) Addr() net.Addr {
+	return pipeAddr("memory")
+}
+
+// DialContext returns the client end of a new connection to the listener.
+// The network and address are ignored.
+func (l *InMemoryListener) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		client.Close()
+		server.Close()
+		return nil, l.opError("dial", ctx.Err())
+	case l.conns <- server:
+		// The listener may have been closed meanwhile
+		select {
+		case <-l.done:
+		default:
+			return client, nil
+		}
+	}
+
+	client.Close()
+	server.Close()
+	return nil, l.opError("dial", net.ErrClosed)
+}
+
+// NewInMemoryConnection returns a new connection to the service accepting
+// connections of the listener.
+func NewInMemoryConnection(ctx context.Context, l *InMemoryListener) (*Connection, error) {
+	conn, err := l.DialContext(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	c := Connection{
+		address: "memory:",
+		conn:    ctxio.NewConn(conn),
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,112 @@
+package varlink_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/varlink/go/varlink"
+)
+
+func TestInMemory(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		product := fmt.Sprintf("In-Memory Test %d", i)
+
+		t.Run(product, func(t *testing.T) {
+			t.Parallel()
+
+			service, err := varlink.NewService("Varlink", product, "1", "https://github.com/varlink/go/varlink")
+			if err != nil {
+				t.Fatalf("NewService(): %v", err)
+			}
+
+			ctx := context.Background()
+			l := varlink.NewInMemoryListener()
+			servererror := make(chan error)
+			go func() {
+				servererror <- service.Serve(ctx, l, 0)
+			}()
+
+			for j := 0; j < 2; j++ {
+				c, err := varlink.NewInMemoryConnection(ctx, l)
+				if err != nil {
+					t.Fatalf("NewInMemoryConnection(): %v", err)
+				}
+
+				var p string
+				if err := c.GetInfo(ctx, nil, &p, nil, nil, nil); err != nil {
+					t.Fatalf("GetInfo(): %v", err)
+				}
+				if p != product {
+					t.Fatalf("GetInfo() returned product '%s'", p)
+				}
+				c.Close()
+			}
+
+			// The listener is usable as dialer
+			c, err := varlink.NewConnectionWithDialer(ctx, "unix:ignored", l)
+			if err != nil {
+				t.Fatalf("NewConnectionWithDialer(): %v", err)
+			}
+			if err := c.GetInfo(ctx, nil, nil, nil, nil, nil); err != nil {
+				t.Fatalf("GetInfo(): %v", err)
+			}
+			c.Close()
+
+			service.Shutdown()
+			if err := <-servererror; err != nil {
+				t.Fatalf("Serve(): %v", err)
+			}
+
+			if _, err := varlink.NewInMemoryConnection(ctx, l); !errors.Is(err, net.ErrClosed) {
+				t.Fatalf("NewInMemoryConnection() on a closed listener returned %v", err)
+			}
+		})
+	}
+}
+
+func TestInMemoryTimeout(t *testing.T) {
+	service, err := varlink.NewService("Varlink", "In-Memory Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+
+	err = service.Serve(context.Background(), varlink.NewInMemoryListener(), 10*time.Millisecond)
+	if _, ok := err.(varlink.ServiceTimeoutError); !ok {
+		t.Fatalf("Serve() returned %v", err)
+	}
+}
+
+func TestInMemoryDeadline(t *testing.T) {
+	l := varlink.NewInMemoryListener()
+	defer l.Close()
+
+	// Changing the deadline affects a pending Accept
+	l.SetDeadline(time.Now().Add(time.Hour))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.SetDeadline(time.Now().Add(10 * time.Millisecond))
+	}()
+
+	_, err := l.Accept()
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Accept() returned %v", err)
+	}
+
+	// The connections support deadlines
+	go l.Accept()
+	conn, err := l.DialContext(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("DialContext(): %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Read() returned %v", err)
+	}
+}
@@ -0,0 +1,423 @@
+// Package websocket implements the parts of the WebSocket protocol (RFC 6455)
+// needed to carry varlink messages: the opening handshake of clients and
+// servers, and the exchange of text and binary messages. Extensions and
+// subprotocols are not supported.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+)
+
+// Frame opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close status codes.
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeInvalidData   = 1007
+	closeTooBig        = 1009
+)
+
+// MaxMessageSize is the maximum size of a received message.
+const MaxMessageSize = 16 << 20
+
+// acceptGUID is appended to the key of the client to compute the accept
+// value of the server.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// aLongTimeAgo is a time in the past that indicates a connection should
+// immediately time out.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// ErrProtocol is returned when the peer violates the protocol.
+var ErrProtocol = errors.New("websocket: protocol error")
+
+// Conn is a WebSocket connection.
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	client bool
+
+	writeMutex sync.Mutex
+	closeSent  bool
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// ReadMessage reads the next text or binary message. Ping frames are
+// answered while waiting for it. When the peer closes the connection,
+// io.EOF is returned.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		messageType int
+		message     []byte
+	)
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+
+		case opPong:
+			continue
+
+		case opClose:
+			status := []byte{}
+			if len(payload) >= 2 {
+				status = payload[:2]
+			}
+			c.writeFrame(opClose, status)
+			return 0, nil, io.EOF
+
+		case opText, opBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(closeProtocolError, ErrProtocol)
+			}
+			messageType = int(opcode)
+
+		case opContinuation:
+			if messageType == 0 {
+				return 0, nil, c.fail(closeProtocolError, ErrProtocol)
+			}
+
+		default:
+			return 0, nil, c.fail(closeProtocolError, ErrProtocol)
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			return 0, nil, c.fail(closeTooBig, fmt.Errorf("websocket: message exceeds %d bytes", MaxMessageSize))
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(closeInvalidData, fmt.Errorf("websocket: invalid UTF-8 in text message"))
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// WriteMessage writes a text or binary message in a single frame.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// Close sends a close frame and closes the underlying connection, without
+// waiting for the close frame of the peer.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, closeStatus(closeNormal))
+	return c.conn.Close()
+}
+
+// fail sends a close frame with the status code and returns err.
+func (c *Conn) fail(status uint16, err error) error {
+	c.writeFrame(opClose, closeStatus(status))
+	return err
+}
+
+func closeStatus(status uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, status)
+}
+
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	// No extensions are negotiated, and clients must mask their frames
+	if header[0]&0x70 != 0 || masked != !c.client {
+		return false, 0, nil, c.fail(closeProtocolError, ErrProtocol)
+	}
+
+	// Control frames must not be fragmented
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, c.fail(closeProtocolError, ErrProtocol)
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > MaxMessageSize {
+		return false, 0, nil, c.fail(closeTooBig, fmt.Errorf("websocket: frame exceeds %d bytes", MaxMessageSize))
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	if opcode == opClose {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for the key.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains reports whether the comma separated list of the header
+// contains the token.
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowedOrigin reports whether the Origin header of the request is one of
+// the origins, or names the host of the request. Requests without Origin
+// header are not sent by browsers and are allowed.
+func allowedOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range origins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade performs the server side of the opening handshake for the request
+// and takes over its connection. If the request is not a valid WebSocket
+// request, an error status is replied and an error is returned.
+//
+// Requests of browsers from other origins than the host of the request are
+// rejected with 403 Forbidden, unless their origin, like
+// https://example.com, is one of the origins.
+func Upgrade(w http.ResponseWriter, r *http.Request, origins []string) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Not a WebSocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("websocket: not a WebSocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, fmt.Errorf("websocket: unsupported version")
+	}
+
+	if !allowedOrigin(r, origins) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket: origin %s not allowed", r.Header.Get("Origin"))
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "Invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, fmt.Errorf("websocket: invalid key")
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "Connection cannot be upgraded", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	// Deadlines set by the http.Server do not apply anymore
+	conn.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, reader: rw.Reader}, nil
+}
+
+// Client performs the client side of the opening handshake on conn for the
+// host and path of the request URI. The context is only used for the
+// handshake.
+func Client(ctx context.Context, conn net.Conn, host string, path string) (*Conn, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	done := make(chan struct{})
+	interrupted := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(aLongTimeAgo)
+		close(done)
+	})
+
+	reader := bufio.NewReader(conn)
+	err = req.Write(conn)
+
+	var resp *http.Response
+	if err == nil {
+		resp, err = http.ReadResponse(reader, req)
+	}
+
+	if !interrupted() {
+		<-done
+		conn.SetDeadline(time.Time{})
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("websocket: handshake failed with status %s", resp.Status)
+	}
+	if !headerContains(resp.Header, "Upgrade", "websocket") ||
+		!headerContains(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("websocket: invalid handshake response")
+	}
+
+	return &Conn{conn: conn, reader: reader, client: true}, nil
+}
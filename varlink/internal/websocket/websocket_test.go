@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newEchoServer returns a server, which echoes all messages.
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r, []string{"https://example.com"})
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		for {
+			messageType, message, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(messageType, message); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server) *Conn {
+	host := strings.TrimPrefix(server.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Client(context.Background(), conn, host, "/")
+	if err != nil {
+		conn.Close()
+		t.Fatalf("Client(): %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// maskedFrame returns a client frame.
+func maskedFrame(fin bool, opcode byte, payload string) []byte {
+	b := opcode
+	if fin {
+		b |= 0x80
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{b, 0x80 | byte(len(payload))}, mask...)
+	for i := range payload {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+	return frame
+}
+
+func TestMessages(t *testing.T) {
+	c := dial(t, newEchoServer(t))
+
+	messages := []struct {
+		messageType int
+		data        []byte
+	}{
+		{TextMessage, []byte(`{"method":"org.varlink.service.GetInfo"}`)},
+		{TextMessage, []byte{}},
+		{BinaryMessage, bytes.Repeat([]byte{0, 1, 2}, 300)},
+		{BinaryMessage, bytes.Repeat([]byte{0xff}, 70000)},
+	}
+
+	for _, m := range messages {
+		if err := c.WriteMessage(m.messageType, m.data); err != nil {
+			t.Fatalf("WriteMessage(): %v", err)
+		}
+		messageType, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage(): %v", err)
+		}
+		if messageType != m.messageType || !bytes.Equal(data, m.data) {
+			t.Fatalf("ReadMessage() returned a different message of %d bytes", len(data))
+		}
+	}
+}
+
+func TestFragments(t *testing.T) {
+	c := dial(t, newEchoServer(t))
+
+	// A fragmented message, interrupted by a ping
+	var frames []byte
+	frames = append(frames, maskedFrame(false, opText, "Hello, ")...)
+	frames = append(frames, maskedFrame(true, opPing, "ping")...)
+	frames = append(frames, maskedFrame(true, opContinuation, "World")...)
+	if _, err := c.NetConn().Write(frames); err != nil {
+		t.Fatal(err)
+	}
+
+	fin, opcode, payload, err := c.readFrame()
+	if err != nil || !fin || opcode != opPong || string(payload) != "ping" {
+		t.Fatalf("readFrame() returned %v %d '%s' %v", fin, opcode, payload, err)
+	}
+
+	messageType, data, err := c.ReadMessage()
+	if err != nil || messageType != TextMessage || string(data) != "Hello, World" {
+		t.Fatalf("ReadMessage() returned %d '%s' %v", messageType, data, err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := dial(t, newEchoServer(t))
+
+	// An unmasked client frame violates the protocol
+	if _, err := c.NetConn().Write([]byte{0x81, 0x01, 'x'}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.ReadMessage(); err != io.EOF {
+		t.Fatalf("ReadMessage() returned %v", err)
+	}
+}
+
+func TestUpgradeRejected(t *testing.T) {
+	server := newEchoServer(t)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("GET returned %s", resp.Status)
+	}
+
+	server = httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := Client(context.Background(), conn, host, "/"); err == nil {
+		t.Fatal("Client() accepted a response without upgrade")
+	}
+}
+
+func TestUpgradeOrigin(t *testing.T) {
+	server := newEchoServer(t)
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	for _, test := range []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://" + host, http.StatusSwitchingProtocols},
+		{"HTTPS://EXAMPLE.COM", http.StatusSwitchingProtocols},
+		{"https://evil.example", http.StatusForbidden},
+		{"https://example.com:8443", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Fatalf("Origin '%s' returned %s", test.origin, resp.Status)
+		}
+	}
+}
@@ -11,6 +11,15 @@ func (e InterfaceNotFound) Error() string {
 	return "org.varlink.service.InterfaceNotFound"
 }
 
+// Is reports whether target is the InterfaceNotFound error, for use with errors.Is.
+func (e InterfaceNotFound) Is(target error) bool {
+	switch target.(type) {
+	case InterfaceNotFound, *InterfaceNotFound:
+		return true
+	}
+	return false
+}
+
 // The requested method was not found
 type MethodNotFound struct {
 	Method string `json:"method"`
@@ -20,6 +29,15 @@ func (e MethodNotFound) Error() string {
 	return "org.varlink.service.MethodNotFound"
 }
 
+// Is reports whether target is the MethodNotFound error, for use with errors.Is.
+func (e MethodNotFound) Is(target error) bool {
+	switch target.(type) {
+	case MethodNotFound, *MethodNotFound:
+		return true
+	}
+	return false
+}
+
 // The interface defines the requested method, but the service does not
 // implement it.
 type MethodNotImplemented struct {
@@ -30,6 +48,15 @@ func (e MethodNotImplemented) Error() string {
 	return "org.varlink.service.MethodNotImplemented"
 }
 
+// Is reports whether target is the MethodNotImplemented error, for use with errors.Is.
+func (e MethodNotImplemented) Is(target error) bool {
+	switch target.(type) {
+	case MethodNotImplemented, *MethodNotImplemented:
+		return true
+	}
+	return false
+}
+
 // One of the passed parameters is invalid.
 type InvalidParameter struct {
 	Parameter string `json:"parameter"`
@@ -39,6 +66,15 @@ func (e InvalidParameter) Error() string {
 	return "org.varlink.service.InvalidParameter"
 }
 
+// Is reports whether target is the InvalidParameter error, for use with errors.Is.
+func (e InvalidParameter) Is(target error) bool {
+	switch target.(type) {
+	case InvalidParameter, *InvalidParameter:
+		return true
+	}
+	return false
+}
+
 func doReplyError(ctx context.Context, c *Call, name string, parameters interface{}) error {
 	return c.sendMessage(ctx, &serviceReply{
 		Error:      name,
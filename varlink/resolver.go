@@ -1,6 +1,12 @@
 package varlink
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
 
 // ResolverAddress is the well-known address of the varlink interface resolver,
 // it translates varlink interface names to varlink service addresses.
@@ -37,37 +43,7 @@ func (r *Resolver) Resolve(ctx context.Context, iface string) (string, error) {
 
 // GetInfo requests information about the resolver.
 func (r *Resolver) GetInfo(ctx context.Context, vendor *string, product *string, version *string, url *string, interfaces *[]string) error {
-	type reply struct {
-		Vendor     string
-		Product    string
-		Version    string
-		URL        string
-		Interfaces []string
-	}
-
-	var rep reply
-	err := r.conn.Call(ctx, "org.varlink.resolver.GetInfo", nil, &rep)
-	if err != nil {
-		return err
-	}
-
-	if vendor != nil {
-		*vendor = rep.Vendor
-	}
-	if product != nil {
-		*product = rep.Product
-	}
-	if version != nil {
-		*version = rep.Version
-	}
-	if url != nil {
-		*url = rep.URL
-	}
-	if interfaces != nil {
-		*interfaces = rep.Interfaces
-	}
-
-	return nil
+	return r.conn.GetInfo(ctx, vendor, product, version, url, interfaces)
 }
 
 // Close terminates the resolver.
@@ -92,3 +68,132 @@ func NewResolver(ctx context.Context, address string) (*Resolver, error) {
 
 	return &r, nil
 }
+
+// DefaultResolverTTL is the time an InterfaceDialer caches resolved
+// addresses, if its TTL is not set.
+const DefaultResolverTTL = time.Minute
+
+// InterfaceDialer connects to the services implementing varlink interfaces.
+// It asks the resolver for their addresses, and caches them. The zero value
+// is ready to use.
+type InterfaceDialer struct {
+	// ResolverAddress is the address of the resolver, ResolverAddress if
+	// empty.
+	ResolverAddress string
+
+	// TTL is the time resolved addresses are cached, DefaultResolverTTL
+	// if zero. A negative TTL disables the cache.
+	TTL time.Duration
+
+	// Static maps interface names to addresses, which are used when the
+	// resolver is not running or does not know the interface.
+	Static map[string]string
+
+	mutex sync.Mutex
+	cache map[string]resolvedAddress
+}
+
+// resolvedAddress is an address cached by an InterfaceDialer.
+type resolvedAddress struct {
+	address string
+	expires time.Time
+}
+
+// DefaultInterfaceDialer is the InterfaceDialer used by DialInterface.
+var DefaultInterfaceDialer = &InterfaceDialer{}
+
+// DialInterface returns a connection to the service implementing the
+// interface, using DefaultInterfaceDialer.
+func DialInterface(ctx context.Context, iface string) (*Connection, error) {
+	return DefaultInterfaceDialer.DialInterface(ctx, iface)
+}
+
+// DialInterface returns a connection to the service implementing the
+// interface. If the cached address cannot be connected to, the interface is
+// resolved again.
+func (d *InterfaceDialer) DialInterface(ctx context.Context, iface string) (*Connection, error) {
+	if address, ok := d.cached(iface); ok {
+		c, err := NewConnection(ctx, address)
+		if err == nil {
+			return c, nil
+		}
+		d.forget(iface)
+	}
+
+	address, err := d.Resolve(ctx, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConnection(ctx, address)
+}
+
+// Resolve returns the address of the service implementing the interface,
+// from the cache, the resolver or the static addresses.
+func (d *InterfaceDialer) Resolve(ctx context.Context, iface string) (string, error) {
+	if address, ok := d.cached(iface); ok {
+		return address, nil
+	}
+
+	address, err := d.resolve(ctx, iface)
+	if err != nil {
+		if static, ok := d.Static[iface]; ok {
+			return static, nil
+		}
+		return "", err
+	}
+
+	if d.TTL >= 0 {
+		ttl := d.TTL
+		if ttl == 0 {
+			ttl = DefaultResolverTTL
+		}
+		d.mutex.Lock()
+		if d.cache == nil {
+			d.cache = make(map[string]resolvedAddress)
+		}
+		d.cache[iface] = resolvedAddress{address: address, expires: time.Now().Add(ttl)}
+		d.mutex.Unlock()
+	}
+
+	return address, nil
+}
+
+// resolve asks the resolver for the address of the interface.
+func (d *InterfaceDialer) resolve(ctx context.Context, iface string) (string, error) {
+	r, err := NewResolver(ctx, d.ResolverAddress)
+	if err != nil {
+		return "", fmt.Errorf("cannot connect to the resolver: %w", err)
+	}
+	defer r.Close()
+
+	address, err := r.Resolve(ctx, iface)
+	if err != nil {
+		return "", err
+	}
+	if address == "" {
+		return "", errors.New("resolver returned an empty address for " + iface)
+	}
+	return address, nil
+}
+
+func (d *InterfaceDialer) cached(iface string) (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	entry, ok := d.cache[iface]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(d.cache, iface)
+		return "", false
+	}
+	return entry.address, true
+}
+
+func (d *InterfaceDialer) forget(iface string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.cache, iface)
+}
@@ -0,0 +1,169 @@
+package varlink_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/varlink/go/varlink"
+)
+
+// resolverInterface resolves interface names with a map.
+type resolverInterface struct {
+	addresses map[string]string
+	calls     atomic.Int32
+}
+
+func (r *resolverInterface) VarlinkDispatch(ctx context.Context, call varlink.Call, methodname string) error {
+	if methodname != "Resolve" {
+		return call.ReplyMethodNotFound(ctx, methodname)
+	}
+	r.calls.Add(1)
+
+	var in struct {
+		Interface string `json:"interface"`
+	}
+	if err := call.GetParameters(&in); err != nil {
+		return call.ReplyInvalidParameter(ctx, "interface")
+	}
+	address, ok := r.addresses[in.Interface]
+	if !ok {
+		return call.ReplyError(ctx, "org.varlink.resolver.InterfaceNotFound", &in)
+	}
+	return call.Reply(ctx, map[string]string{"address": address})
+}
+
+func (r *resolverInterface) VarlinkGetName() string {
+	return `org.varlink.resolver`
+}
+
+func (r *resolverInterface) VarlinkGetDescription() string {
+	return `interface org.varlink.resolver
+
+method Resolve(interface: string) -> (address: string)
+
+error InterfaceNotFound (interface: string)
+`
+}
+
+// listenService starts a service with the interface on a unix socket in dir.
+func listenService(t *testing.T, product string, iface interface {
+	VarlinkDispatch(context.Context, varlink.Call, string) error
+	VarlinkGetName() string
+	VarlinkGetDescription() string
+}) string {
+	service, err := varlink.NewService("Varlink", product, "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(iface); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+
+	address := "unix:" + filepath.Join(t.TempDir(), "socket")
+	if err := service.Bind(context.Background(), address); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+
+	servererror := make(chan error)
+	go func() {
+		servererror <- service.DoListen(context.Background(), 0)
+	}()
+	t.Cleanup(func() {
+		service.Shutdown()
+		if err := <-servererror; err != nil {
+			t.Errorf("DoListen(): %v", err)
+		}
+	})
+
+	return address
+}
+
+func TestDialInterface(t *testing.T) {
+	ctx := context.Background()
+
+	serviceAddress := listenService(t, "Resolved Service", new(VarlinkInterface))
+	resolver := &resolverInterface{addresses: map[string]string{"org.example.test": serviceAddress}}
+	resolverAddress := listenService(t, "Test Resolver", resolver)
+
+	dial := func(d *varlink.InterfaceDialer, iface string, expected string) {
+		t.Helper()
+		c, err := d.DialInterface(ctx, iface)
+		if err != nil {
+			t.Fatalf("DialInterface(%s): %v", iface, err)
+		}
+		defer c.Close()
+
+		var product string
+		if err := c.GetInfo(ctx, nil, &product, nil, nil, nil); err != nil {
+			t.Fatalf("GetInfo(): %v", err)
+		}
+		if product != expected {
+			t.Fatalf("DialInterface(%s) connected to '%s'", iface, product)
+		}
+	}
+
+	t.Run("Cache", func(t *testing.T) {
+		d := &varlink.InterfaceDialer{ResolverAddress: resolverAddress}
+		resolver.calls.Store(0)
+		dial(d, "org.example.test", "Resolved Service")
+		dial(d, "org.example.test", "Resolved Service")
+		if calls := resolver.calls.Load(); calls != 1 {
+			t.Fatalf("Resolver was called %d times", calls)
+		}
+
+		// The resolver does not resolve itself
+		dial(d, "org.varlink.resolver", "Test Resolver")
+		if calls := resolver.calls.Load(); calls != 1 {
+			t.Fatalf("Resolver was called %d times", calls)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		d := &varlink.InterfaceDialer{ResolverAddress: resolverAddress, TTL: time.Millisecond}
+		resolver.calls.Store(0)
+		dial(d, "org.example.test", "Resolved Service")
+		time.Sleep(2 * time.Millisecond)
+		dial(d, "org.example.test", "Resolved Service")
+		if calls := resolver.calls.Load(); calls != 2 {
+			t.Fatalf("Resolver was called %d times", calls)
+		}
+	})
+
+	t.Run("Static", func(t *testing.T) {
+		d := &varlink.InterfaceDialer{
+			ResolverAddress: "unix:" + filepath.Join(t.TempDir(), "missing"),
+			Static:          map[string]string{"org.example.test": serviceAddress},
+		}
+		dial(d, "org.example.test", "Resolved Service")
+
+		if c, err := d.DialInterface(ctx, "org.example.unknown"); err == nil {
+			c.Close()
+			t.Fatal("DialInterface() of an unknown interface did not fail")
+		}
+
+		// Interfaces unknown to a running resolver
+		d.ResolverAddress = resolverAddress
+		d.Static = map[string]string{"org.example.static": serviceAddress}
+		dial(d, "org.example.static", "Resolved Service")
+	})
+
+	t.Run("GetInfo", func(t *testing.T) {
+		r, err := varlink.NewResolver(ctx, resolverAddress)
+		if err != nil {
+			t.Fatalf("NewResolver(): %v", err)
+		}
+		defer r.Close()
+
+		var product string
+		var interfaces []string
+		if err := r.GetInfo(ctx, nil, &product, nil, nil, &interfaces); err != nil {
+			t.Fatalf("GetInfo(): %v", err)
+		}
+		if product != "Test Resolver" || len(interfaces) != 2 {
+			t.Fatalf("GetInfo() returned '%s' %v", product, interfaces)
+		}
+	})
+}
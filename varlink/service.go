@@ -2,14 +2,18 @@ package varlink
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/varlink/go/varlink/idl"
 	"github.com/varlink/go/varlink/internal/ctxio"
 )
 
@@ -44,12 +48,14 @@ type Service struct {
 	interfaces   map[string]dispatcher
 	names        []string
 	descriptions map[string]string
+	idls         map[string]*idl.IDL
+	validate     bool
 	running      bool
 	listener     net.Listener
 	conncounter  int64
 	mutex        sync.Mutex
-	protocol     string
-	address      string
+	address      *Address
+	tlsConfig    *tls.Config
 }
 
 // ServiceTimeoutError helps API users to special-case timeouts.
@@ -88,6 +94,7 @@ func (s *Service) HandleMessage(ctx context.Context, conn ReadWriterContext, req
 		Conn:    conn,
 		In:      &in,
 		Request: &request,
+		state:   &callState{},
 	}
 
 	r := strings.LastIndex(in.Method, ".")
@@ -108,80 +115,192 @@ func (s *Service) HandleMessage(ctx context.Context, conn ReadWriterContext, req
 		return c.ReplyInterfaceNotFound(ctx, interfacename)
 	}
 
-	return iface.VarlinkDispatch(ctx, c, methodname)
+	if s.validate {
+		if parameter := s.invalidParameter(interfacename, methodname, in.Parameters); parameter != "" {
+			return c.ReplyInvalidParameter(ctx, parameter)
+		}
+	}
+
+	err = iface.VarlinkDispatch(ctx, c, methodname)
+	if err == nil && c.state.continued && !c.state.finished {
+		// The client waits for the end of the stream
+		return fmt.Errorf("method %s returned without a final reply", in.Method)
+	}
+	return err
+}
+
+// invalidParameter checks the call parameters against the input of the method
+// in the registered interface description. It returns the path of the first
+// invalid field, or an empty string if the parameters are valid or the
+// description does not define the method.
+func (s *Service) invalidParameter(interfacename string, methodname string, parameters *json.RawMessage) string {
+	midl := s.idls[interfacename]
+	if midl == nil {
+		return ""
+	}
+
+	method := midl.LookupMethod(methodname)
+	if method == nil {
+		return ""
+	}
+
+	value := json.RawMessage("{}")
+	if parameters != nil && string(*parameters) != "null" {
+		value = *parameters
+	}
+
+	err := method.In.Validate(value, midl)
+	if err == nil {
+		return ""
+	}
+
+	if verr, ok := err.(*idl.ValidationError); ok && verr.Path != "" {
+		return fieldPath(value, verr.Path)
+	}
+	return "parameters"
+}
+
+// fieldPath converts the JSON pointer to a value in parameters to the path of
+// the field, like config.items[2].name.
+func fieldPath(parameters json.RawMessage, pointer string) string {
+	var v interface{}
+	json.Unmarshal(parameters, &v)
+
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	var path strings.Builder
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = unescape.Replace(token)
+		if array, ok := v.([]interface{}); ok {
+			path.WriteString("[" + token + "]")
+			v = nil
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(array) {
+				v = array[i]
+			}
+			continue
+		}
+
+		if path.Len() > 0 {
+			path.WriteString(".")
+		}
+		path.WriteString(token)
+		object, _ := v.(map[string]interface{})
+		v = object[token]
+	}
+
+	return path.String()
+}
+
+// SetParameterValidation enables or disables the validation of incoming method
+// calls against the registered interface descriptions. Calls with parameters
+// not matching the method input are answered with an InvalidParameter error
+// naming the path of the offending field, like config.items[2].name, before
+// the method is dispatched. While validation is enabled, RegisterInterface
+// fails for interfaces whose description can not be parsed; interfaces
+// registered before are not validated if their description is invalid. It
+// must be called before the service is started.
+func (s *Service) SetParameterValidation(enabled bool) {
+	s.validate = enabled
 }
 
 // Shutdown shuts down the listener of a running service.
 func (s *Service) Shutdown() error {
-	s.running = false
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.running = false
 	if s.listener == nil {
 		return nil
 	}
 	return s.listener.Close()
 }
 
-func (s *Service) handleConnection(ctx context.Context, conn net.Conn, wg *sync.WaitGroup) {
+func (s *Service) isRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.running
+}
+
+func (s *Service) handleConnection(ctx context.Context, conn net.Conn, tlsConfig *tls.Config, wg *sync.WaitGroup) {
 	defer func() { s.mutex.Lock(); s.conncounter--; s.mutex.Unlock(); wg.Done() }()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+
+	if tlsConfig != nil {
+		tlsConn := tls.Server(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return
+		}
+		conn = tlsConn
+	}
+
+	s.serve(ctx, conn)
+}
+
+// serve handles the messages of a connection until the peer closes it, the
+// context is done, or a message cannot be handled. It closes the connection.
+func (s *Service) serve(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
 	ctxConn := ctxio.NewConn(conn)
 
 	for {
 		request, err := ctxConn.ReadBytes(ctx, '\x00')
 		if err != nil {
-			break
+			if err == io.EOF {
+				return nil
+			}
+			return err
 		}
 
 		err = s.HandleMessage(ctx, ctxConn, request[:len(request)-1])
 		if err != nil {
-			// FIXME: report error
-			// fmt.Fprintf(os.Stderr, "handleMessage: %v", err)
-			break
+			return err
 		}
 	}
+}
 
-	conn.Close()
+// ServeConn serves a single connection, which was established by other means
+// than a listener of the Service, like a pipe or a connection of another
+// server. It returns nil when the peer closes the connection, and closes the
+// connection before returning.
+func (s *Service) ServeConn(ctx context.Context, conn net.Conn) error {
+	return s.serve(ctx, conn)
 }
 
 func (s *Service) teardown() {
 	s.mutex.Lock()
 	s.listener = nil
 	s.running = false
-	s.protocol = ""
-	s.address = ""
+	s.address = nil
 	s.mutex.Unlock()
 }
 
 func (s *Service) parseAddress(address string) error {
-	words := strings.SplitN(address, ":", 2)
-	if len(words) != 2 {
-		return fmt.Errorf("Unknown protocol")
+	addr, err := ParseAddress(address)
+	if err != nil {
+		return err
 	}
 
-	s.protocol = words[0]
-	s.address = words[1]
-
-	// Ignore parameters after ';'
-	words = strings.SplitN(s.address, ";", 2)
-	if words != nil {
-		s.address = words[0]
+	if _, err := lookupTransport(addr.Scheme); err != nil {
+		return err
 	}
 
-	switch s.protocol {
-	case "unix":
-		break
-	case "tcp":
-		break
-
-	default:
-		return fmt.Errorf("Unknown protocol")
+	if addr.Scheme == "tls" && s.tlsConfig == nil {
+		return fmt.Errorf("tls address without TLS configuration, see SetTLSConfig()")
 	}
 
+	s.address = addr
 	return nil
 }
 
+// SetTLSConfig sets the TLS configuration of a service listening on a tls:
+// address, which is a TCP address like tcp:. The configuration needs a
+// server certificate; to authenticate clients with their certificates, set
+// ClientAuth to tls.RequireAndVerifyClientCert and ClientCAs, and look at
+// Call.PeerCertificate. It must be called before the service is bound.
+func (s *Service) SetTLSConfig(config *tls.Config) {
+	s.tlsConfig = config
+}
+
 func (s *Service) GetListener() (net.Listener, error) {
 	s.mutex.Lock()
 	l := s.listener
@@ -192,18 +311,14 @@ func (s *Service) GetListener() (net.Listener, error) {
 func (s *Service) setListener(ctx context.Context) error {
 	l := activationListener()
 	if l == nil {
-		if s.protocol == "unix" && s.address[0] != '@' {
-			os.Remove(s.address)
-		}
-
-		var err error
-		l, err = listen(ctx, s.protocol, s.address)
+		t, err := lookupTransport(s.address.Scheme)
 		if err != nil {
 			return err
 		}
 
-		if s.protocol == "unix" && s.address[0] != '@' {
-			l.(*net.UnixListener).SetUnlinkOnClose(true)
+		l, err = t.Listen(ctx, s.address)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -236,9 +351,12 @@ func (s *Service) Bind(ctx context.Context, address string) error {
 	}
 	s.mutex.Unlock()
 
-	s.parseAddress(address)
+	err := s.parseAddress(address)
+	if err != nil {
+		return err
+	}
 
-	err := s.setListener(ctx)
+	err = s.setListener(ctx)
 	if err != nil {
 		return err
 	}
@@ -247,49 +365,28 @@ func (s *Service) Bind(ctx context.Context, address string) error {
 
 // Listen starts a Service.
 func (s *Service) Listen(ctx context.Context, address string, timeout time.Duration) error {
-	var wg sync.WaitGroup
-	defer func() { s.teardown(); wg.Wait() }()
-
 	err := s.Bind(ctx, address)
 	if err != nil {
+		s.teardown()
 		return err
 	}
 
-	s.mutex.Lock()
-	s.running = true
-	l := s.listener
-	s.mutex.Unlock()
+	return s.DoListen(ctx, timeout)
+}
 
-	for s.running {
-		if timeout != 0 {
-			if err := s.refreshTimeout(timeout); err != nil {
-				return err
-			}
-		}
-		conn, err := l.Accept()
-		if err != nil {
-			if err.(net.Error).Timeout() {
-				s.mutex.Lock()
-				if s.conncounter == 0 {
-					s.mutex.Unlock()
-					return ServiceTimeoutError{}
-				}
-				s.mutex.Unlock()
-				continue
-			}
-			if !s.running {
-				return nil
-			}
-			return err
-		}
-		s.mutex.Lock()
-		s.conncounter++
+// Serve starts a Service accepting connections from the listener, like one
+// returned by NewInMemoryListener. The listener is closed by Shutdown.
+func (s *Service) Serve(ctx context.Context, l net.Listener, timeout time.Duration) error {
+	s.mutex.Lock()
+	if s.running {
 		s.mutex.Unlock()
-		wg.Add(1)
-		go s.handleConnection(ctx, conn, &wg)
+		return fmt.Errorf("Serve(): already running")
 	}
+	s.address = nil
+	s.listener = l
+	s.mutex.Unlock()
 
-	return nil
+	return s.DoListen(ctx, timeout)
 }
 
 // DoListen starts a Service.
@@ -297,8 +394,13 @@ func (s *Service) DoListen(ctx context.Context, timeout time.Duration) error {
 	var wg sync.WaitGroup
 	defer func() { s.teardown(); wg.Wait() }()
 
+	// Connections of tls: addresses are handshaked by the service
+	var tlsConfig *tls.Config
 	s.mutex.Lock()
 	l := s.listener
+	if s.address != nil && s.address.Scheme == "tls" {
+		tlsConfig = s.tlsConfig
+	}
 	s.mutex.Unlock()
 
 	if l == nil {
@@ -309,7 +411,7 @@ func (s *Service) DoListen(ctx context.Context, timeout time.Duration) error {
 	s.running = true
 	s.mutex.Unlock()
 
-	for s.running {
+	for s.isRunning() {
 		if timeout != 0 {
 			if err := s.refreshTimeout(timeout); err != nil {
 				return err
@@ -317,7 +419,8 @@ func (s *Service) DoListen(ctx context.Context, timeout time.Duration) error {
 		}
 		conn, err := l.Accept()
 		if err != nil {
-			if err.(net.Error).Timeout() {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
 				s.mutex.Lock()
 				if s.conncounter == 0 {
 					s.mutex.Unlock()
@@ -326,7 +429,7 @@ func (s *Service) DoListen(ctx context.Context, timeout time.Duration) error {
 				s.mutex.Unlock()
 				continue
 			}
-			if !s.running {
+			if !s.isRunning() {
 				return nil
 			}
 			return err
@@ -335,7 +438,7 @@ func (s *Service) DoListen(ctx context.Context, timeout time.Duration) error {
 		s.conncounter++
 		s.mutex.Unlock()
 		wg.Add(1)
-		go s.handleConnection(ctx, conn, &wg)
+		go s.handleConnection(ctx, conn, tlsConfig, &wg)
 	}
 
 	return nil
@@ -348,11 +451,21 @@ func (s *Service) RegisterInterface(iface dispatcher) error {
 		return fmt.Errorf("interface '%s' already registered", name)
 	}
 
-	if s.running {
+	if s.isRunning() {
 		return fmt.Errorf("service is already running")
 	}
+	description := iface.VarlinkGetDescription()
+	midl, err := idl.New(description)
+	if err != nil && s.validate {
+		return fmt.Errorf("interface '%s': %v", name, err)
+	}
+
 	s.interfaces[name] = iface
-	s.descriptions[name] = iface.VarlinkGetDescription()
+	s.descriptions[name] = description
+	// Interfaces without a valid description are not validated
+	if err == nil {
+		s.idls[name] = midl
+	}
 	s.names = append(s.names, name)
 
 	return nil
@@ -367,6 +480,7 @@ func NewService(vendor string, product string, version string, url string) (*Ser
 		url:          url,
 		interfaces:   make(map[string]dispatcher),
 		descriptions: make(map[string]string),
+		idls:         make(map[string]*idl.IDL),
 	}
 	err := s.RegisterInterface(orgvarlinkserviceNew())
 
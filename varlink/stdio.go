@@ -0,0 +1,69 @@
+package varlink
+
+import (
+	"context"
+	"net"
+	"os"
+	"time"
+)
+
+var _ net.Conn = &stdioConn{}
+
+// stdioConn is a net.Conn reading from one file and writing to another.
+type stdioConn struct {
+	in  *os.File
+	out *os.File
+}
+
+func (c *stdioConn) Read(b []byte) (int, error) {
+	return c.in.Read(b)
+}
+
+func (c *stdioConn) Write(b []byte) (int, error) {
+	return c.out.Write(b)
+}
+
+func (c *stdioConn) Close() error {
+	err1 := c.in.Close()
+	err2 := c.out.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+func (c *stdioConn) LocalAddr() net.Addr {
+	return pipeAddr("stdio")
+}
+
+func (c *stdioConn) RemoteAddr() net.Addr {
+	return pipeAddr("stdio")
+}
+
+// Deadlines are only supported by files in non-blocking mode, errors are
+// returned for all others.
+func (c *stdioConn) SetDeadline(t time.Time) error {
+	if err := c.in.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.out.SetWriteDeadline(t)
+}
+
+func (c *stdioConn) SetReadDeadline(t time.Time) error {
+	return c.in.SetReadDeadline(t)
+}
+
+func (c *stdioConn) SetWriteDeadline(t time.Time) error {
+	return c.out.SetWriteDeadline(t)
+}
+
+// ServeStdio serves exactly one connection over os.Stdin and os.Stdout, like
+// a service started by a bridge, e.g. "ssh host my-service --bridge". It
+// returns when the client closes its end of the connection. Stdin and stdout
+// are closed on return; nothing else must be written to stdout meanwhile.
+//
+// Stdin is usually in blocking mode, a done context then does not interrupt
+// a pending read, it is only noticed when the next message arrives.
+func (s *Service) ServeStdio(ctx context.Context) error {
+	return s.ServeConn(ctx, &stdioConn{in: os.Stdin, out: os.Stdout})
+}
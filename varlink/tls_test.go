@@ -0,0 +1,210 @@
+package varlink_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/varlink/go/varlink"
+)
+
+func init() {
+	// A registered transport, which does not use TLS
+	varlink.RegisterTransport("test-plain", &memoryTransport{varlink.NewInMemoryListener()})
+}
+
+// newCertificate returns a certificate signed by parent, or a self-signed CA
+// certificate if parent is nil.
+func newCertificate(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// peerInterface replies with the common name of the client certificate.
+type peerInterface struct{}
+
+func (s *peerInterface) VarlinkDispatch(ctx context.Context, call varlink.Call, methodname string) error {
+	var out struct {
+		Name string `json:"name"`
+	}
+	if cert := call.PeerCertificate(); cert != nil {
+		out.Name = cert.Subject.CommonName
+	}
+	return call.Reply(ctx, &out)
+}
+
+func (s *peerInterface) VarlinkGetName() string {
+	return `org.example.peer`
+}
+
+func (s *peerInterface) VarlinkGetDescription() string {
+	return "interface org.example.peer\n\nmethod Get() -> (name: string)"
+}
+
+func TestTLS(t *testing.T) {
+	ca := newCertificate(t, "CA", nil)
+	server := newCertificate(t, "server", &ca)
+	client := newCertificate(t, "client", &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	service, err := varlink.NewService("Varlink", "Varlink Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(new(peerInterface)); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := service.Bind(ctx, "tls:127.0.0.1:0"); err == nil {
+		t.Fatal("Bind() accepted a tls address without TLS configuration")
+	}
+
+	service.SetTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+	})
+	if err := service.Bind(ctx, "tls:127.0.0.1:0"); err != nil {
+		t.Fatalf("Bind(): %v", err)
+	}
+	l, _ := service.GetListener()
+	address := "tls:" + l.Addr().String()
+
+	servererror := make(chan error)
+	go func() {
+		servererror <- service.DoListen(ctx, 0)
+	}()
+
+	get := func(c *varlink.Connection) (string, error) {
+		var out struct {
+			Name string `json:"name"`
+		}
+		err := c.Call(ctx, "org.example.peer.Get", nil, &out)
+		return out.Name, err
+	}
+
+	t.Run("MutualTLS", func(t *testing.T) {
+		c, err := varlink.NewConnectionWithTLS(ctx, address, &tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{client},
+		})
+		if err != nil {
+			t.Fatalf("NewConnectionWithTLS(): %v", err)
+		}
+		defer c.Close()
+
+		name, err := get(c)
+		if err != nil {
+			t.Fatalf("Call(): %v", err)
+		}
+		if name != "client" {
+			t.Fatalf("PeerCertificate() returned '%s'", name)
+		}
+	})
+
+	t.Run("WithoutCertificate", func(t *testing.T) {
+		c, err := varlink.NewConnectionWithTLS(ctx, address, &tls.Config{RootCAs: pool})
+		if err != nil {
+			t.Fatalf("NewConnectionWithTLS(): %v", err)
+		}
+		defer c.Close()
+
+		name, err := get(c)
+		if err != nil {
+			t.Fatalf("Call(): %v", err)
+		}
+		if name != "" {
+			t.Fatalf("PeerCertificate() returned '%s'", name)
+		}
+	})
+
+	t.Run("UnknownServer", func(t *testing.T) {
+		c, err := varlink.NewConnection(ctx, address)
+		if err == nil {
+			c.Close()
+			t.Fatal("NewConnection() accepted a server certificate of an unknown CA")
+		}
+	})
+
+	t.Run("UnknownClient", func(t *testing.T) {
+		other := newCertificate(t, "other", nil)
+		c, err := varlink.NewConnectionWithTLS(ctx, address, &tls.Config{
+			RootCAs: pool,
+			// Send the certificate, although the service does not accept
+			// its CA
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &other, nil
+			},
+		})
+		if err == nil {
+			// With TLS 1.3 the server rejects the certificate after the
+			// client finished the handshake
+			defer c.Close()
+			_, err = get(c)
+		}
+		if err == nil {
+			t.Fatal("Service accepted a client certificate of an unknown CA")
+		}
+	})
+
+	t.Run("NoTLS", func(t *testing.T) {
+		for _, address := range []string{"tcp:" + strings.TrimPrefix(address, "tls:"), "ws:" + strings.TrimPrefix(address, "tls:"), "test-plain:foo"} {
+			c, err := varlink.NewConnectionWithTLS(ctx, address, &tls.Config{RootCAs: pool})
+			if err == nil {
+				c.Close()
+			}
+			if err == nil || !strings.Contains(err.Error(), "do not use TLS") {
+				t.Fatalf("NewConnectionWithTLS(%s) returned %v", address, err)
+			}
+		}
+	})
+
+	service.Shutdown()
+	if err := <-servererror; err != nil {
+		t.Fatalf("DoListen(): %v", err)
+	}
+}
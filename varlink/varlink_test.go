@@ -278,3 +278,205 @@ func TestMoreService(t *testing.T) {
 			string(written))
 	})
 }
+
+type validatedInterface struct{}
+
+func (s *validatedInterface) VarlinkDispatch(ctx context.Context, call Call, methodname string) error {
+	return call.Reply(ctx, nil)
+}
+
+func (s *validatedInterface) VarlinkGetName() string {
+	return `org.example.validate`
+}
+
+func (s *validatedInterface) VarlinkGetDescription() string {
+	return `interface org.example.validate
+
+type Item (id: int, state: (idle, busy))
+
+method Set(item: Item, note: ?string, tags: ?[]Item) -> ()
+`
+}
+
+// invalidInterface has a description, which can not be parsed.
+type invalidInterface struct{}
+
+func (s *invalidInterface) VarlinkDispatch(ctx context.Context, call Call, methodname string) error {
+	return call.Reply(ctx, nil)
+}
+
+func (s *invalidInterface) VarlinkGetName() string {
+	return `org.example.invalid`
+}
+
+func (s *invalidInterface) VarlinkGetDescription() string {
+	return `interface org.example.invalid`
+}
+
+func TestParameterValidation(t *testing.T) {
+	service, _ := NewService(
+		"Varlink",
+		"Varlink Test",
+		"1",
+		"https://github.com/varlink/go/varlink",
+	)
+
+	if err := service.RegisterInterface(new(validatedInterface)); err != nil {
+		t.Fatalf("Couldn't register service: %v", err)
+	}
+	service.SetParameterValidation(true)
+	if err := service.RegisterInterface(new(invalidInterface)); err == nil {
+		t.Fatal("RegisterInterface() accepted an invalid description")
+	}
+
+	tests := []struct {
+		name     string
+		msg      string
+		expected string
+	}{
+		{"Valid", `{"method":"org.example.validate.Set","parameters":{"item":{"id":1,"state":"idle"}}}`,
+			`{}`},
+		{"WrongType", `{"method":"org.example.validate.Set","parameters":{"item":{"id":"1","state":"idle"}}}`,
+			`{"parameters":{"parameter":"item.id"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"WrongEnum", `{"method":"org.example.validate.Set","parameters":{"item":{"id":1,"state":"gone"}}}`,
+			`{"parameters":{"parameter":"item.state"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"Missing", `{"method":"org.example.validate.Set"}`,
+			`{"parameters":{"parameter":"item"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"Unknown", `{"method":"org.example.validate.Set","parameters":{"item":{"id":1,"state":"idle"},"foo":1}}`,
+			`{"parameters":{"parameter":"foo"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"ArrayElement", `{"method":"org.example.validate.Set","parameters":{"item":{"id":1,"state":"idle"},"tags":[{"id":1,"state":"idle"},{"id":2}]}}`,
+			`{"parameters":{"parameter":"tags[1].state"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"Escaped", `{"method":"org.example.validate.Set","parameters":{"item":{"id":1,"state":"idle"},"a/b~c":1}}`,
+			`{"parameters":{"parameter":"a/b~c"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"NotStruct", `{"method":"org.example.validate.Set","parameters":[]}`,
+			`{"parameters":{"parameter":"parameters"},"error":"org.varlink.service.InvalidParameter"}`},
+		{"UnknownMethod", `{"method":"org.example.validate.Get","parameters":{"foo":1}}`,
+			`{}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var written []byte
+			wf := readWriterContextFunc(func(ctx context.Context, in []byte) (int, error) {
+				written = append(written, in...)
+				return len(in), nil
+			})
+			if err := service.HandleMessage(context.Background(), wf, []byte(test.msg)); err != nil {
+				t.Fatalf("HandleMessage returned error: %v", err)
+			}
+			expect(t, test.expected+"\000", string(written))
+		})
+	}
+}
+
+type streamInterface struct {
+	err error // the error returned by the last reply
+}
+
+func (s *streamInterface) VarlinkDispatch(ctx context.Context, call Call, methodname string) error {
+	switch methodname {
+	case "Stream":
+		for i := 0; i < 2; i++ {
+			if s.err = call.Stream(ctx, nil); s.err != nil {
+				return s.err
+			}
+		}
+		s.err = call.Finish(ctx, nil)
+		return s.err
+
+	case "Twice":
+		if err := call.Finish(ctx, nil); err != nil {
+			return err
+		}
+		s.err = call.Reply(ctx, nil)
+		return nil
+
+	case "Unfinished":
+		return call.Stream(ctx, nil)
+	}
+
+	return call.ReplyMethodNotImplemented(ctx, methodname)
+}
+
+func (s *streamInterface) VarlinkGetName() string {
+	return `org.example.stream`
+}
+
+func (s *streamInterface) VarlinkGetDescription() string {
+	return `interface org.example.stream
+
+method Stream() -> ()
+method Twice() -> ()
+method Unfinished() -> ()
+`
+}
+
+func TestStream(t *testing.T) {
+	iface := new(streamInterface)
+	service, _ := NewService(
+		"Varlink",
+		"Varlink Test",
+		"1",
+		"https://github.com/varlink/go/varlink",
+	)
+	if err := service.RegisterInterface(iface); err != nil {
+		t.Fatalf("Couldn't register service: %v", err)
+	}
+
+	handle := func(msg string, fail int) (string, error) {
+		var written []byte
+		wf := readWriterContextFunc(func(ctx context.Context, in []byte) (int, error) {
+			if fail == 0 {
+				return 0, fmt.Errorf("disconnected")
+			}
+			fail--
+			written = append(written, in...)
+			return len(in), nil
+		})
+		err := service.HandleMessage(context.Background(), wf, []byte(msg))
+		return string(written), err
+	}
+
+	t.Run("Stream", func(t *testing.T) {
+		written, err := handle(`{"method":"org.example.stream.Stream","more":true}`, -1)
+		if err != nil {
+			t.Fatalf("HandleMessage returned error: %v", err)
+		}
+		expect(t, `{"continues":true}`+"\000"+`{"continues":true}`+"\000"+`{}`+"\000", written)
+	})
+
+	t.Run("NoMore", func(t *testing.T) {
+		written, err := handle(`{"method":"org.example.stream.Stream"}`, -1)
+		if err != ErrMoreNotRequested {
+			t.Fatalf("HandleMessage returned: %v", err)
+		}
+		expect(t, "", written)
+	})
+
+	t.Run("Twice", func(t *testing.T) {
+		written, err := handle(`{"method":"org.example.stream.Twice"}`, -1)
+		if err != nil {
+			t.Fatalf("HandleMessage returned error: %v", err)
+		}
+		if iface.err != ErrCallFinished {
+			t.Fatalf("Second reply returned: %v", iface.err)
+		}
+		expect(t, `{}`+"\000", written)
+	})
+
+	t.Run("Unfinished", func(t *testing.T) {
+		written, err := handle(`{"method":"org.example.stream.Unfinished","more":true}`, -1)
+		if err == nil {
+			t.Fatal("HandleMessage accepted a stream without a final reply")
+		}
+		expect(t, `{"continues":true}`+"\000", written)
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		written, err := handle(`{"method":"org.example.stream.Stream","more":true}`, 1)
+		if err == nil || err.Error() != "disconnected" {
+			t.Fatalf("HandleMessage returned: %v", err)
+		}
+		expect(t, `{"continues":true}`+"\000", written)
+	})
+}
@@ -0,0 +1,61 @@
+package varlink
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// vsockAny is VMADDR_CID_ANY and VMADDR_PORT_ANY, which bind to any CID or
+// to a free port.
+const vsockAny = 0xffffffff
+
+// VsockAddr is the address of an AF_VSOCK socket, which connects virtual
+// machines and their host. The context identifier (CID) of the host is 2,
+// 1 is the local loopback (VMADDR_CID_LOCAL).
+type VsockAddr struct {
+	CID  uint32
+	Port uint32
+}
+
+// Network returns "vsock".
+func (a *VsockAddr) Network() string {
+	return "vsock"
+}
+
+// String returns the address as CID:PORT.
+func (a *VsockAddr) String() string {
+	return fmt.Sprintf("%d:%d", a.CID, a.Port)
+}
+
+// parseVsockAddress parses the CID:PORT part of a vsock: address. A service
+// may leave out the CID to listen on any CID, and the port to listen on a
+// free port.
+func parseVsockAddress(address string, listen bool) (*VsockAddr, error) {
+	words := strings.SplitN(address, ":", 2)
+	if len(words) != 2 {
+		return nil, fmt.Errorf("invalid vsock address '%s', expected CID:PORT", address)
+	}
+
+	parse := func(s string, name string) (uint32, error) {
+		if s == "" && listen {
+			return vsockAny, nil
+		}
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s in vsock address '%s'", name, address)
+		}
+		return uint32(v), nil
+	}
+
+	cid, err := parse(words[0], "CID")
+	if err != nil {
+		return nil, err
+	}
+	port, err := parse(words[1], "port")
+	if err != nil {
+		return nil, err
+	}
+
+	return &VsockAddr{CID: cid, Port: port}, nil
+}
@@ -0,0 +1,267 @@
+//go:build linux && !386
+
+package varlink
+
+import (
+	"context"
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// afVsock is AF_VSOCK, which is not defined by package syscall.
+const afVsock = 40
+
+// rawSockaddrVM is struct sockaddr_vm.
+type rawSockaddrVM struct {
+	Family    uint16
+	Reserved1 uint16
+	Port      uint32
+	CID       uint32
+	Flags     uint8
+	Zero      [3]uint8
+}
+
+func vsockSocket() (int, error) {
+	fd, err := syscall.Socket(afVsock, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, 0)
+	if err != nil {
+		return -1, os.NewSyscallError("socket", err)
+	}
+	return fd, nil
+}
+
+// vsockSockaddr calls connect or bind with the address.
+func vsockSockaddr(trap uintptr, fd int, addr *VsockAddr) error {
+	sa := rawSockaddrVM{Family: afVsock, Port: addr.Port, CID: addr.CID}
+	_, _, errno := syscall.Syscall(trap, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// vsockName calls getsockname or getpeername.
+func vsockName(trap uintptr, fd int) (*VsockAddr, error) {
+	var sa rawSockaddrVM
+	n := uint32(unsafe.Sizeof(sa))
+	_, _, errno := syscall.Syscall(trap, uintptr(fd), uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&n)))
+	if errno != 0 {
+		return nil, errno
+	}
+	return &VsockAddr{CID: sa.CID, Port: sa.Port}, nil
+}
+
+var _ net.Conn = &vsockConn{}
+
+// vsockConn is a connected AF_VSOCK socket. The file is registered with the
+// runtime poller, which implements deadlines.
+type vsockConn struct {
+	*os.File
+	local  *VsockAddr
+	remote *VsockAddr
+}
+
+func (c *vsockConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *vsockConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// newVsockConn returns a connection for the connected socket fd.
+func newVsockConn(fd int) (*vsockConn, error) {
+	f := os.NewFile(uintptr(fd), "vsock")
+	c, err := newVsockConnFile(f, fd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newVsockConnFile returns a connection for the file of the connected socket
+// fd.
+func newVsockConnFile(f *os.File, fd int) (*vsockConn, error) {
+	local, err := vsockName(syscall.SYS_GETSOCKNAME, fd)
+	if err != nil {
+		return nil, os.NewSyscallError("getsockname", err)
+	}
+	remote, err := vsockName(syscall.SYS_GETPEERNAME, fd)
+	if err != nil {
+		return nil, os.NewSyscallError("getpeername", err)
+	}
+
+	return &vsockConn{File: f, local: local, remote: remote}, nil
+}
+
+// dialVsock connects to the address. The context interrupts a pending
+// connect.
+func dialVsock(ctx context.Context, addr *VsockAddr) (net.Conn, error) {
+	fd, err := vsockSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	err = vsockSockaddr(syscall.SYS_CONNECT, fd, addr)
+	if err == nil {
+		return newVsockConn(fd)
+	}
+	if err != syscall.EINPROGRESS {
+		syscall.Close(fd)
+		return nil, &net.OpError{Op: "dial", Net: "vsock", Addr: addr, Err: os.NewSyscallError("connect", err)}
+	}
+
+	f := os.NewFile(uintptr(fd), "vsock")
+	if err := waitConnected(ctx, f); err != nil {
+		f.Close()
+		return nil, &net.OpError{Op: "dial", Net: "vsock", Addr: addr, Err: err}
+	}
+
+	c, err := newVsockConnFile(f, fd)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// waitConnected waits for the pending connect of the socket to finish.
+func waitConnected(ctx context.Context, f *os.File) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		f.SetWriteDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		f.SetWriteDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+	defer f.SetWriteDeadline(time.Time{})
+
+	// The socket becomes writable, when the connect finished
+	var connErr error
+	waited := false
+	err = rc.Write(func(fd uintptr) bool {
+		if !waited {
+			waited = true
+			return false
+		}
+
+		v, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_ERROR)
+		if err == nil && v != 0 {
+			err = syscall.Errno(v)
+		}
+		if err != nil {
+			connErr = os.NewSyscallError("connect", err)
+			return true
+		}
+
+		// Woken up before connecting
+		_, err = vsockName(syscall.SYS_GETPEERNAME, int(fd))
+		return err != syscall.ENOTCONN
+	})
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return connErr
+}
+
+var _ net.Listener = &vsockListener{}
+
+// vsockListener is a listening AF_VSOCK socket.
+type vsockListener struct {
+	f    *os.File
+	addr *VsockAddr
+}
+
+// listenVsock returns a listener on the address.
+func listenVsock(addr *VsockAddr) (net.Listener, error) {
+	fd, err := vsockSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vsockSockaddr(syscall.SYS_BIND, fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, &net.OpError{Op: "listen", Net: "vsock", Addr: addr, Err: os.NewSyscallError("bind", err)}
+	}
+
+	if err := syscall.Listen(fd, syscall.SOMAXCONN); err != nil {
+		syscall.Close(fd)
+		return nil, &net.OpError{Op: "listen", Net: "vsock", Addr: addr, Err: os.NewSyscallError("listen", err)}
+	}
+
+	local, err := vsockName(syscall.SYS_GETSOCKNAME, fd)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("getsockname", err)
+	}
+
+	return &vsockListener{
+		f:    os.NewFile(uintptr(fd), "vsock:"+local.String()),
+		addr: local,
+	}, nil
+}
+
+// Accept waits for and returns the next connection.
+func (l *vsockListener) Accept() (net.Conn, error) {
+	rc, err := l.f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	nfd := -1
+	var acceptErr error
+	err = rc.Read(func(fd uintptr) bool {
+		for {
+			r, _, errno := syscall.Syscall6(syscall.SYS_ACCEPT4, fd, 0, 0,
+				syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, 0, 0)
+			switch errno {
+			case 0:
+				nfd = int(r)
+				return true
+			case syscall.EINTR, syscall.ECONNABORTED:
+				continue
+			case syscall.EAGAIN:
+				return false
+			default:
+				acceptErr = os.NewSyscallError("accept4", errno)
+				return true
+			}
+		}
+	})
+
+	if err == nil {
+		err = acceptErr
+	}
+	if err != nil {
+		return nil, &net.OpError{Op: "accept", Net: "vsock", Addr: l.addr, Err: err}
+	}
+
+	return newVsockConn(nfd)
+}
+
+// Close closes the listener.
+func (l *vsockListener) Close() error {
+	return l.f.Close()
+}
+
+// Addr returns the address of the listener.
+func (l *vsockListener) Addr() net.Addr {
+	return l.addr
+}
+
+// SetDeadline sets the deadline for Accept.
+func (l *vsockListener) SetDeadline(t time.Time) error {
+	return l.f.SetReadDeadline(t)
+}
@@ -0,0 +1,89 @@
+//go:build linux && !386
+
+package varlink_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/varlink/go/varlink"
+)
+
+// cidInterface replies with the CID of the client.
+type cidInterface struct{}
+
+func (s *cidInterface) VarlinkDispatch(ctx context.Context, call varlink.Call, methodname string) error {
+	var out struct {
+		CID int64 `json:"cid"`
+	}
+	out.CID = -1
+	if cid, ok := call.PeerCID(); ok {
+		out.CID = int64(cid)
+	}
+	return call.Reply(ctx, &out)
+}
+
+func (s *cidInterface) VarlinkGetName() string {
+	return `org.example.cid`
+}
+
+func (s *cidInterface) VarlinkGetDescription() string {
+	return "interface org.example.cid\n\nmethod Get() -> (cid: int)"
+}
+
+func TestVsock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, address := range []string{"vsock:1", "vsock:1:port", "vsock::1"} {
+		if c, err := varlink.NewConnection(ctx, address); err == nil {
+			c.Close()
+			t.Fatalf("NewConnection() accepted '%s'", address)
+		}
+	}
+
+	service, err := varlink.NewService("Varlink", "Vsock Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(new(cidInterface)); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+
+	// Listen on a free port of the local loopback, VMADDR_CID_LOCAL
+	if err := service.Bind(ctx, "vsock:1:"); err != nil {
+		t.Skipf("vsock loopback is not available: %v", err)
+	}
+	l, _ := service.GetListener()
+	addr, ok := l.Addr().(*varlink.VsockAddr)
+	if !ok || addr.CID != 1 {
+		t.Fatalf("Listener has address %v", l.Addr())
+	}
+
+	servererror := make(chan error)
+	go func() {
+		servererror <- service.DoListen(ctx, 0)
+	}()
+
+	c, err := varlink.NewConnection(ctx, fmt.Sprintf("vsock:1:%d", addr.Port))
+	if err != nil {
+		t.Fatalf("NewConnection(): %v", err)
+	}
+
+	var out struct {
+		CID int64 `json:"cid"`
+	}
+	if err := c.Call(ctx, "org.example.cid.Get", nil, &out); err != nil {
+		t.Fatalf("Call(): %v", err)
+	}
+	if out.CID != 1 {
+		t.Fatalf("PeerCID() returned %d", out.CID)
+	}
+	c.Close()
+
+	service.Shutdown()
+	if err := <-servererror; err != nil {
+		t.Fatalf("DoListen(): %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux || 386
+
+package varlink
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+func dialVsock(ctx context.Context, addr *VsockAddr) (net.Conn, error) {
+	return nil, fmt.Errorf("vsock: addresses are not supported on this platform")
+}
+
+func listenVsock(addr *VsockAddr) (net.Listener, error) {
+	return nil, fmt.Errorf("vsock: addresses are not supported on this platform")
+}
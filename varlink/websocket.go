@@ -0,0 +1,167 @@
+package varlink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/varlink/go/varlink/internal/websocket"
+)
+
+var _ net.Conn = &wsConn{}
+
+// wsConn is a varlink connection over WebSocket. Every varlink message is
+// carried in a text message, without the terminating NUL byte.
+type wsConn struct {
+	ws   *websocket.Conn
+	rbuf []byte
+	wbuf []byte
+}
+
+func (c *wsConn) Read(b []byte) (int, error) {
+	for len(c.rbuf) == 0 {
+		_, message, err := c.ws.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+		// A NUL byte would end the varlink message early, and let the
+		// rest pass as another message
+		if bytes.IndexByte(message, 0) >= 0 {
+			c.ws.Close()
+			return 0, errors.New("websocket: message contains a NUL byte")
+		}
+		c.rbuf = append(message, 0)
+	}
+
+	n := copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+// Write sends every message terminated by a NUL byte in b, and buffers the
+// rest. If sending fails, the returned count only includes the bytes of b,
+// which were sent, and the unsent bytes of b are dropped from the buffer.
+func (c *wsConn) Write(b []byte) (int, error) {
+	pending := len(c.wbuf)
+	c.wbuf = append(c.wbuf, b...)
+
+	sent := 0
+	for {
+		i := bytes.IndexByte(c.wbuf[sent:], 0)
+		if i < 0 {
+			break
+		}
+		if err := c.ws.WriteMessage(websocket.TextMessage, c.wbuf[sent:sent+i]); err != nil {
+			if sent < pending {
+				c.wbuf = c.wbuf[sent:pending]
+				return 0, err
+			}
+			c.wbuf = c.wbuf[:0]
+			return sent - pending, err
+		}
+		sent += i + 1
+	}
+	c.wbuf = c.wbuf[sent:]
+
+	return len(b), nil
+}
+
+func (c *wsConn) Close() error {
+	return c.ws.Close()
+}
+
+func (c *wsConn) LocalAddr() net.Addr {
+	return c.ws.NetConn().LocalAddr()
+}
+
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.ws.NetConn().RemoteAddr()
+}
+
+func (c *wsConn) SetDeadline(t time.Time) error {
+	return c.ws.NetConn().SetDeadline(t)
+}
+
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	return c.ws.NetConn().SetReadDeadline(t)
+}
+
+func (c *wsConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.NetConn().SetWriteDeadline(t)
+}
+
+// WebSocketHandler returns an http.Handler, which upgrades requests to
+// WebSocket connections and serves them. Every varlink message is carried in
+// a text message, without the terminating NUL byte. The handler can be
+// combined with other handlers of an http.Server, like:
+//
+//	http.Handle("/varlink", service.WebSocketHandler())
+//	http.ListenAndServe(":8080", nil)
+//
+// Clients connect to ws:host:8080/varlink addresses.
+//
+// Browsers send the origin of the page opening a WebSocket connection. To
+// protect against cross-site WebSocket hijacking, connections from other
+// origins than the host of the request are rejected with 403 Forbidden,
+// unless their origin is one of the origins, like https://example.com.
+// Clients other than browsers do not send an origin and are not affected.
+func (s *Service) WebSocketHandler(origins ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Upgrade(w, r, origins)
+		if err != nil {
+			return
+		}
+		s.ServeConn(r.Context(), &wsConn{ws: ws})
+	})
+}
+
+// parseWebSocketAddress splits the address of a ws: or wss: address, like
+// example.com:8080/varlink, into the host with port and the path. The
+// default port is 80 for ws: and 443 for wss:.
+func parseWebSocketAddress(protocol string, addr string) (string, string) {
+	addr = strings.TrimPrefix(addr, "//")
+
+	host, path := addr, "/"
+	if i := strings.IndexByte(addr, '/'); i >= 0 {
+		host, path = addr[:i], addr[i:]
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "80"
+		if protocol == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+
+	return host, path
+}
+
+// dialWebSocket connects to ws: and wss: addresses.
+func dialWebSocket(ctx context.Context, dialer ContextDialer, config *tls.Config, addr *Address) (net.Conn, error) {
+	host, path := parseWebSocketAddress(addr.Scheme, addr.Addr)
+
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	if addr.Scheme == "wss" {
+		conn, err = clientTLS(ctx, conn, host, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ws, err := websocket.Client(ctx, conn, host, path)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{ws: ws}, nil
+}
@@ -0,0 +1,145 @@
+package varlink_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/varlink/go/varlink"
+	"github.com/varlink/go/varlink/internal/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	service, err := varlink.NewService("Varlink", "WebSocket Test", "1", "https://github.com/varlink/go/varlink")
+	if err != nil {
+		t.Fatalf("NewService(): %v", err)
+	}
+	if err := service.RegisterInterface(new(VarlinkInterface)); err != nil {
+		t.Fatalf("RegisterInterface(): %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/varlink", service.WebSocketHandler())
+	mux.Handle("/allowed", service.WebSocketHandler("https://example.com"))
+
+	ctx := context.Background()
+
+	check := func(t *testing.T, c *varlink.Connection) {
+		defer c.Close()
+
+		var product string
+		for i := 0; i < 2; i++ {
+			if err := c.GetInfo(ctx, nil, &product, nil, nil, nil); err != nil {
+				t.Fatalf("GetInfo(): %v", err)
+			}
+			if product != "WebSocket Test" {
+				t.Fatalf("GetInfo() returned product '%s'", product)
+			}
+		}
+
+		description, err := c.GetInterfaceDescription(ctx, "org.varlink.service")
+		if err != nil {
+			t.Fatalf("GetInterfaceDescription(): %v", err)
+		}
+		if !strings.Contains(description, "interface org.varlink.service") {
+			t.Fatalf("GetInterfaceDescription() returned '%s'", description)
+		}
+	}
+
+	t.Run("WS", func(t *testing.T) {
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		address := "ws:" + strings.TrimPrefix(server.URL, "http://") + "/varlink"
+		c, err := varlink.NewConnection(ctx, address)
+		if err != nil {
+			t.Fatalf("NewConnection(): %v", err)
+		}
+		check(t, c)
+
+		if c, err := varlink.NewConnection(ctx, "ws:"+strings.TrimPrefix(server.URL, "http://")+"/other"); err == nil {
+			c.Close()
+			t.Fatal("NewConnection() succeeded without a WebSocket endpoint")
+		}
+	})
+
+	t.Run("WSS", func(t *testing.T) {
+		server := httptest.NewTLSServer(mux)
+		defer server.Close()
+
+		pool := x509.NewCertPool()
+		pool.AddCert(server.Certificate())
+
+		address := "wss://" + strings.TrimPrefix(server.URL, "https://") + "/varlink"
+		c, err := varlink.NewConnectionWithTLS(ctx, address, &tls.Config{RootCAs: pool, ServerName: "example.com"})
+		if err != nil {
+			t.Fatalf("NewConnectionWithTLS(): %v", err)
+		}
+		check(t, c)
+	})
+
+	t.Run("Origin", func(t *testing.T) {
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		upgrade := func(path string, origin string) int {
+			req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Origin", origin)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		if status := upgrade("/varlink", server.URL); status != http.StatusSwitchingProtocols {
+			t.Fatalf("Same origin returned %d", status)
+		}
+		if status := upgrade("/varlink", "https://example.com"); status != http.StatusForbidden {
+			t.Fatalf("Cross origin returned %d", status)
+		}
+		if status := upgrade("/allowed", "https://example.com"); status != http.StatusSwitchingProtocols {
+			t.Fatalf("Allowed origin returned %d", status)
+		}
+	})
+
+	t.Run("NUL", func(t *testing.T) {
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		host := strings.TrimPrefix(server.URL, "http://")
+		conn, err := net.Dial("tcp", host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ws, err := websocket.Client(ctx, conn, host, "/varlink")
+		if err != nil {
+			conn.Close()
+			t.Fatalf("Client(): %v", err)
+		}
+		defer ws.Close()
+
+		// Two calls smuggled in one message
+		call := `{"method":"org.varlink.service.GetInfo"}`
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(call+"\x00"+call)); err != nil {
+			t.Fatalf("WriteMessage(): %v", err)
+		}
+		if _, message, err := ws.ReadMessage(); err == nil {
+			t.Fatalf("Service replied '%s'", message)
+		}
+	})
+}
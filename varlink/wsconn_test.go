@@ -0,0 +1,82 @@
+package varlink
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/varlink/go/varlink/internal/websocket"
+)
+
+var errWriteFailed = errors.New("write failed")
+
+// failingConn fails writing, once the number of writes is used up. A
+// negative number never fails.
+type failingConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *failingConn) Write(b []byte) (int, error) {
+	if c.writes == 0 {
+		return 0, errWriteFailed
+	}
+	if c.writes > 0 {
+		c.writes--
+	}
+	return c.Conn.Write(b)
+}
+
+func TestWebSocketWriteCount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &failingConn{Conn: conn, writes: -1}
+	ws, err := websocket.Client(context.Background(), fc, host, "/")
+	if err != nil {
+		conn.Close()
+		t.Fatalf("Client(): %v", err)
+	}
+	defer conn.Close()
+	c := &wsConn{ws: ws}
+
+	write := func(b string, writes int, count int, pending string) {
+		t.Helper()
+		fc.writes = writes
+		n, err := c.Write([]byte(b))
+		if n != count || (n < len(b)) != (err != nil) {
+			t.Fatalf("Write(%q) returned %d, %v", b, n, err)
+		}
+		if string(c.wbuf) != pending {
+			t.Fatalf("Write(%q) left %q in the buffer", b, c.wbuf)
+		}
+	}
+
+	// The first message, from the earlier write, is sent, the second fails
+	write("abc", 0, 3, "abc")
+	write("\x00de\x00f", 1, 1, "")
+	// The pending message fails, nothing of b is sent
+	write("abc", 0, 3, "abc")
+	write("\x00", 0, 0, "abc")
+	write("\x00", 1, 1, "")
+}